@@ -3,7 +3,9 @@ module zabbix-agent2-apt-updates
 go 1.24.10
 
 require (
+	github.com/pierrec/lz4/v4 v4.1.31
 	github.com/stretchr/testify v1.11.1
+	github.com/ulikunitz/xz v0.5.17
 	golang.zabbix.com/sdk v1.2.2-0.20251205121637-3b95c058c0e4
 )
 
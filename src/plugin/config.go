@@ -19,6 +19,9 @@
 package plugin
 
 import (
+	"sort"
+
+	"zabbix-agent2-apt-updates/src/plugin/handlers"
 	"golang.zabbix.com/sdk/conf"
 	"golang.zabbix.com/sdk/errs"
 	"golang.zabbix.com/sdk/plugin"
@@ -27,12 +30,32 @@ import (
 type session struct {
 }
 
+// ruleConfig is a classification rule assigning the matching updates to a category.
+// Patterns are regular expressions; all set patterns have to match.
+type ruleConfig struct {
+	// Category is a built-in update type (security, recommended, optional) or a custom category name.
+	Category  string
+	Origin    string `conf:"optional"`
+	Label     string `conf:"optional"`
+	Suite     string `conf:"optional"`
+	Component string `conf:"optional"`
+	Section   string `conf:"optional"`
+	Package   string `conf:"optional"`
+}
+
 type pluginConfig struct {
 	System plugin.SystemOptions `conf:"optional"` //nolint:staticcheck
 	// Timeout.
 	// Note: With Zabbix 7.0+, timeout can be configured at the item level (1-600 seconds).
 	// This configuration option is maintained for backwards compatibility and defaults to global timeout.
 	Timeout int `conf:"optional"`
+	// RefreshInterval is the number of seconds between background update checks.
+	// Metrics are served from the snapshot of the last check.
+	RefreshInterval int `conf:"optional,range=60:86400,default=1800"`
+	// RefreshTimeout is the number of seconds a single background update check may take.
+	RefreshTimeout int `conf:"optional,range=10:3600,default=300"`
+	// Rules stores named classification rules, applied in order of their names.
+	Rules map[string]ruleConfig `conf:"optional"`
 	// Sessions stores pre-defined named sets of connection settings.
 	Sessions map[string]session `conf:"optional"`
 	// Default stores default parameter values from configuration file.
@@ -61,6 +84,11 @@ func (p *APTUpdatesPlugin) Configure(global *plugin.GlobalOptions, options any)
 			p.config.Timeout = 10
 		}
 	}
+
+	err = p.handler.SetRules(p.config.classificationRules())
+	if err != nil {
+		p.Errf("cannot apply classification rules: %s", err.Error())
+	}
 }
 
 // Validate implements the Configurator interface.
@@ -73,5 +101,37 @@ func (*APTUpdatesPlugin) Validate(options any) error {
 		return errs.Wrap(err, "failed to unmarshal configuration options")
 	}
 
+	err = handlers.ValidateRules(opts.classificationRules())
+	if err != nil {
+		return errs.Wrap(err, "invalid classification rules")
+	}
+
 	return nil
 }
+
+// classificationRules returns the configured rules sorted by name
+func (c *pluginConfig) classificationRules() []handlers.ClassificationRule {
+	names := make([]string, 0, len(c.Rules))
+	for name := range c.Rules {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	rules := make([]handlers.ClassificationRule, 0, len(names))
+	for _, name := range names {
+		rule := c.Rules[name]
+		rules = append(rules, handlers.ClassificationRule{
+			Name:      name,
+			Category:  rule.Category,
+			Origin:    rule.Origin,
+			Label:     rule.Label,
+			Suite:     rule.Suite,
+			Component: rule.Component,
+			Section:   rule.Section,
+			Package:   rule.Package,
+		})
+	}
+
+	return rules
+}
@@ -0,0 +1,112 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// aptConfig holds the APT configuration as printed by apt-config dump, keyed by
+// the lower-cased option name since APT options are case-insensitive
+type aptConfig map[string][]string
+
+// readAptConfig returns the APT configuration with the files in /etc/apt/apt.conf.d
+// applied in the order apt reads them
+func (h *Handler) readAptConfig(ctx context.Context) (aptConfig, error) {
+	output, err := h.sysCalls.execCommand(ctx, "apt-config", "dump")
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to execute apt-config dump")
+	}
+
+	return parseAptConfig(string(output)), nil
+}
+
+// parseAptConfig parses the output of apt-config dump. Scalar options have a single
+// value, list entries are printed with an empty last name component:
+//
+//	APT::Periodic::Unattended-Upgrade "1";
+//	Unattended-Upgrade::Allowed-Origins "";
+//	Unattended-Upgrade::Allowed-Origins:: "${distro_id}:${distro_codename}-security";
+func parseAptConfig(output string) aptConfig {
+	config := make(aptConfig)
+
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSuffix(value, ";")
+		value = strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`)
+		key = strings.ToLower(key)
+
+		if list, isList := strings.CutSuffix(key, "::"); isList {
+			config[list] = append(config[list], value)
+
+			continue
+		}
+
+		if _, ok := config[key]; !ok || value != "" {
+			// Lists are announced with an empty value before their entries
+			config[key] = []string{value}
+		}
+	}
+
+	return config
+}
+
+// value returns the value of a scalar option, empty if it is not set
+func (c aptConfig) value(key string) string {
+	values := c[strings.ToLower(key)]
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[len(values)-1]
+}
+
+// list returns the entries of a list option, never nil
+func (c aptConfig) list(key string) []string {
+	entries := []string{}
+	for _, entry := range c[strings.ToLower(key)] {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// parseAptBool parses a boolean the way apt's StringToBool does, returning
+// fallback for values it does not recognize
+func parseAptBool(value string, fallback bool) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "0", "no", "false", "without", "off", "disable":
+		return false
+	case "1", "yes", "true", "with", "on", "enable":
+		return true
+	}
+
+	return fallback
+}
@@ -0,0 +1,309 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// extendedStatesFile records which packages apt installed automatically as dependencies
+	extendedStatesFile = "/var/lib/apt/extended_states"
+	// sectionAutoremovable names the autoremovable packages in AllUpdatesResult.SectionErrors
+	sectionAutoremovable = "autoremovable"
+)
+
+// AutoremovablePackage is an automatically installed package nothing depends on anymore
+type AutoremovablePackage struct {
+	Name          string `json:"name"`
+	Architecture  string `json:"architecture"`
+	Version       string `json:"version"`
+	InstalledSize int64  `json:"installed_size"` // Bytes, from the package's Installed-Size
+}
+
+// AutoremovableResult lists the packages apt autoremove would remove
+type AutoremovableResult struct {
+	Count   int                    `json:"count"`
+	List    []string               `json:"list"`
+	Details []AutoremovablePackage `json:"details"`
+}
+
+// GetAutoremovable returns the automatically installed packages that are no longer needed
+func (h *Handler) GetAutoremovable(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.AutoremovableCount == nil {
+		return nil, result.sectionError(sectionAutoremovable, "failed to determine autoremovable packages")
+	}
+
+	return &AutoremovableResult{
+		Count:   *result.AutoremovableCount,
+		List:    result.AutoremovableList,
+		Details: result.AutoremovableDetails,
+	}, nil
+}
+
+// autoremovablePackages marks the packages reachable from the manually installed ones
+// the way apt does and returns the automatically installed packages left unmarked.
+// Roots are the packages without the Auto-Installed flag, essential, protected and
+// held packages, the APT::NeverAutoRemove patterns and the running, latest and
+// previous kernels. Depends and Pre-Depends are followed, Recommends and Suggests
+// unless APT::AutoRemove::RecommendsImportant or SuggestsImportant is false.
+// Version constraints are not checked, every installed alternative is kept.
+func (h *Handler) autoremovablePackages(ctx context.Context, db dpkgDatabase) ([]AutoremovablePackage, error) {
+	auto, err := h.readExtendedStates()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := h.readAptConfig(ctx)
+	if err != nil {
+		// Without the configuration only the built-in roots are kept
+		config = aptConfig{}
+	}
+
+	var neverAutoRemove []*regexp.Regexp
+	for _, pattern := range config.list("APT::NeverAutoRemove") {
+		if re, err := regexp.Compile(pattern); err == nil {
+			neverAutoRemove = append(neverAutoRemove, re)
+		}
+	}
+
+	protectedKernels := protectedKernelReleases(h.runningKernel(), installedKernels(db))
+
+	providers := make(map[string][]string)
+	for name, entries := range db {
+		for _, pkg := range entries {
+			if pkg.IsInstalled() {
+				for _, virtual := range pkg.provides {
+					providers[virtual] = append(providers[virtual], name)
+				}
+			}
+		}
+	}
+
+	marked := make(map[string]bool)
+	var queue []string
+
+	mark := func(name string) {
+		if !marked[name] {
+			marked[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	for name, entries := range db {
+		for _, pkg := range entries {
+			if !pkg.IsInstalled() {
+				continue
+			}
+
+			// Required packages are kept like in apt's MarkRequired
+			if !auto.isAuto(pkg) || pkg.Essential || pkg.Protected || pkg.Priority == "required" || pkg.IsHeld() ||
+				matchesAny(neverAutoRemove, name) || isProtectedKernelPackage(name, protectedKernels) {
+				mark(name)
+			}
+		}
+	}
+
+	followRecommends := config.value("APT::AutoRemove::RecommendsImportant") != "false"
+	followSuggests := config.value("APT::AutoRemove::SuggestsImportant") != "false"
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		for _, pkg := range db[name] {
+			if !pkg.IsInstalled() {
+				continue
+			}
+
+			relations := slices.Clone(pkg.depends)
+			if followRecommends {
+				relations = append(relations, pkg.recommends...)
+			}
+
+			if followSuggests {
+				relations = append(relations, pkg.suggests...)
+			}
+
+			for _, alternatives := range relations {
+				for _, dependency := range alternatives {
+					if _, ok := db.installed(dependency); ok {
+						mark(dependency)
+					}
+
+					for _, provider := range providers[dependency] {
+						mark(provider)
+					}
+				}
+			}
+		}
+	}
+
+	removable := []AutoremovablePackage{}
+	for name, entries := range db {
+		if marked[name] {
+			continue
+		}
+
+		for _, pkg := range entries {
+			if pkg.IsInstalled() {
+				removable = append(removable, AutoremovablePackage{
+					Name:          pkg.Name,
+					Architecture:  pkg.Architecture,
+					Version:       pkg.Version,
+					InstalledSize: pkg.installedSize * 1024,
+				})
+			}
+		}
+	}
+
+	sort.Slice(removable, func(i, j int) bool {
+		if removable[i].Name != removable[j].Name {
+			return removable[i].Name < removable[j].Name
+		}
+
+		return removable[i].Architecture < removable[j].Architecture
+	})
+
+	return removable, nil
+}
+
+// autoInstalled maps package names to the architectures they were automatically installed for
+type autoInstalled map[string]map[string]bool
+
+// isAuto reports whether the package was installed automatically. apt records
+// Architecture: all packages under the native architecture.
+func (a autoInstalled) isAuto(pkg InstalledPackage) bool {
+	archs := a[pkg.Name]
+
+	return archs[pkg.Architecture] || (pkg.Architecture == "all" && len(archs) > 0)
+}
+
+// readExtendedStates returns the automatically installed packages
+func (h *Handler) readExtendedStates() (autoInstalled, error) {
+	auto := make(autoInstalled)
+
+	data, err := h.readFile(extendedStatesFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Nothing was ever installed as a dependency
+			return auto, nil
+		}
+
+		return nil, errs.Wrap(err, "failed to read apt extended states")
+	}
+
+	err = parseDeb822(bytes.NewReader(data), func(stanza deb822Stanza) error {
+		if stanza["Auto-Installed"] != "1" {
+			return nil
+		}
+
+		if auto[stanza["Package"]] == nil {
+			auto[stanza["Package"]] = make(map[string]bool)
+		}
+
+		auto[stanza["Package"]][stanza["Architecture"]] = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to parse apt extended states")
+	}
+
+	return auto, nil
+}
+
+// runningKernel returns the release of the running kernel, empty if it cannot be read
+func (h *Handler) runningKernel() string {
+	data, err := h.readFile(kernelReleaseFile)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// protectedKernelReleases returns the kernel releases apt keeps: the running, the
+// latest and the previous one, without their flavour (6.8.0-51-generic becomes 6.8.0-51)
+func protectedKernelReleases(running string, kernels []KernelPackage) []string {
+	var releases []string
+	if running != "" {
+		releases = append(releases, running)
+	}
+
+	for i := max(len(kernels)-2, 0); i < len(kernels); i++ {
+		releases = append(releases, kernels[i].Release)
+	}
+
+	var protected []string
+	for _, release := range releases {
+		// Drop the flavour: trailing parts not starting with a digit
+		parts := strings.Split(release, "-")
+		for len(parts) > 1 && (parts[len(parts)-1] == "" || !isDigit(parts[len(parts)-1][0])) {
+			parts = parts[:len(parts)-1]
+		}
+
+		if base := strings.Join(parts, "-"); base != "" {
+			protected = append(protected, base)
+		}
+	}
+
+	return protected
+}
+
+// isProtectedKernelPackage reports whether a package belongs to one of the protected
+// kernel releases, e.g. linux-image-6.8.0-51-generic or linux-headers-6.8.0-51
+func isProtectedKernelPackage(name string, releases []string) bool {
+	if !strings.HasPrefix(name, "linux-") {
+		return false
+	}
+
+	for _, release := range releases {
+		if strings.HasSuffix(name, "-"+release) || strings.Contains(name, "-"+release+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesAny reports whether name matches one of the regular expressions
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
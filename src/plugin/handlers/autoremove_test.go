@@ -0,0 +1,332 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAutoremoveStatus = `Package: nginx
+Status: install ok installed
+Architecture: amd64
+Version: 1.24.0-2ubuntu7.1
+Depends: nginx-common (= 1.24.0-2ubuntu7.1), libssl3t64 (>= 3.0.0) | libssl3
+Recommends: ssl-cert
+
+Package: nginx-common
+Status: install ok installed
+Architecture: all
+Version: 1.24.0-2ubuntu7.1
+Suggests: fcgiwrap
+
+Package: libssl3t64
+Status: install ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.5
+Provides: libssl3 (= 3.0.13-0ubuntu3.5)
+
+Package: ssl-cert
+Status: install ok installed
+Architecture: all
+Version: 1.1.2ubuntu1
+
+Package: logwatch
+Status: install ok installed
+Architecture: all
+Version: 7.7-1
+Depends: perl:any, mail-transport-agent
+
+Package: postfix
+Status: install ok installed
+Architecture: amd64
+Version: 3.8.6-1build2
+Provides: mail-transport-agent
+
+Package: libold1
+Status: install ok installed
+Architecture: amd64
+Installed-Size: 512
+Version: 1.0-1
+
+Package: libpinned1
+Status: hold ok installed
+Architecture: amd64
+Version: 1.0-1
+
+Package: bash
+Essential: yes
+Status: install ok installed
+Architecture: amd64
+Version: 5.2.21-2ubuntu4
+
+Package: firmware-linux-free
+Status: install ok installed
+Architecture: all
+Version: 20200122-3
+
+Package: e2fsprogs
+Status: install ok installed
+Priority: required
+Architecture: amd64
+Version: 1.47.0-2
+Depends: libext2fs2 (= 1.47.0-2), logsave
+
+Package: libext2fs2
+Status: install ok installed
+Priority: optional
+Architecture: amd64
+Version: 1.47.0-2
+
+Package: logsave
+Status: install ok installed
+Priority: optional
+Architecture: amd64
+Version: 1.47.0-2
+
+Package: linux-image-6.8.0-40-generic
+Status: install ok installed
+Architecture: amd64
+Installed-Size: 15000
+Version: 6.8.0-40.40
+
+Package: linux-modules-6.8.0-40-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-40.40
+
+Package: linux-headers-6.8.0-40
+Status: install ok installed
+Architecture: all
+Version: 6.8.0-40.40
+
+Package: linux-image-6.8.0-45-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-45.45
+
+Package: linux-image-6.8.0-51-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-51.52
+
+Package: linux-headers-6.8.0-51
+Status: install ok installed
+Architecture: all
+Version: 6.8.0-51.52
+
+Package: libgone1
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 0.9-1
+`
+
+// The native architecture is recorded for Architecture: all packages
+const testExtendedStates = `Package: nginx-common
+Architecture: amd64
+Auto-Installed: 1
+
+Package: libssl3t64
+Architecture: amd64
+Auto-Installed: 1
+
+Package: ssl-cert
+Architecture: amd64
+Auto-Installed: 1
+
+Package: postfix
+Architecture: amd64
+Auto-Installed: 1
+
+Package: libold1
+Architecture: amd64
+Auto-Installed: 1
+
+Package: libpinned1
+Architecture: amd64
+Auto-Installed: 1
+
+Package: bash
+Architecture: amd64
+Auto-Installed: 1
+
+Package: firmware-linux-free
+Architecture: amd64
+Auto-Installed: 1
+
+Package: e2fsprogs
+Architecture: amd64
+Auto-Installed: 1
+
+Package: libext2fs2
+Architecture: amd64
+Auto-Installed: 1
+
+Package: logsave
+Architecture: amd64
+Auto-Installed: 1
+
+Package: linux-image-6.8.0-40-generic
+Architecture: amd64
+Auto-Installed: 1
+
+Package: linux-modules-6.8.0-40-generic
+Architecture: amd64
+Auto-Installed: 1
+
+Package: linux-headers-6.8.0-40
+Architecture: amd64
+Auto-Installed: 1
+
+Package: linux-image-6.8.0-45-generic
+Architecture: amd64
+Auto-Installed: 1
+
+Package: linux-image-6.8.0-51-generic
+Architecture: amd64
+Auto-Installed: 1
+
+Package: linux-headers-6.8.0-51
+Architecture: amd64
+Auto-Installed: 1
+
+Package: libgone1
+Architecture: amd64
+Auto-Installed: 1
+
+Package: logwatch
+Architecture: amd64
+Auto-Installed: 0
+`
+
+// newAutoremoveHandler returns a handler reading testAutoremoveStatus with the given apt-config output
+func newAutoremoveHandler(aptConfig string) *Handler {
+	return &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{
+			"var/lib/dpkg/status":         {Data: []byte(testAutoremoveStatus)},
+			"var/lib/apt/extended_states": {Data: []byte(testExtendedStates)},
+			"proc/sys/kernel/osrelease":   {Data: []byte("6.8.0-51-generic\n")},
+		},
+		output: aptConfig,
+	}}
+}
+
+// TestAutoremovablePackages ensures the automatically installed packages no root depends on are reported
+func TestAutoremovablePackages(t *testing.T) {
+	handler := newAutoremoveHandler("APT::NeverAutoRemove \"\";\nAPT::NeverAutoRemove:: \"^firmware-linux.*\";\n")
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	removable, err := handler.autoremovablePackages(context.Background(), db)
+	require.NoError(t, err)
+
+	assert.Equal(t, []AutoremovablePackage{
+		{Name: "libold1", Architecture: "amd64", Version: "1.0-1", InstalledSize: 512 * 1024},
+		{Name: "linux-headers-6.8.0-40", Architecture: "all", Version: "6.8.0-40.40"},
+		{Name: "linux-image-6.8.0-40-generic", Architecture: "amd64", Version: "6.8.0-40.40", InstalledSize: 15000 * 1024},
+		{Name: "linux-modules-6.8.0-40-generic", Architecture: "amd64", Version: "6.8.0-40.40"},
+	}, removable)
+}
+
+// TestAutoremovableRequiredPackages ensures auto-installed packages of required priority
+// and their dependencies are kept
+func TestAutoremovableRequiredPackages(t *testing.T) {
+	handler := newAutoremoveHandler("")
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+	require.Equal(t, "required", db["e2fsprogs"][0].Priority)
+
+	removable, err := handler.autoremovablePackages(context.Background(), db)
+	require.NoError(t, err)
+
+	for _, pkg := range removable {
+		assert.NotContains(t, []string{"e2fsprogs", "libext2fs2", "logsave"}, pkg.Name)
+	}
+}
+
+// TestAutoremovablePackagesWithoutRecommends ensures recommended packages are only kept while RecommendsImportant is set
+func TestAutoremovablePackagesWithoutRecommends(t *testing.T) {
+	handler := newAutoremoveHandler("APT::AutoRemove::RecommendsImportant \"false\";\n")
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	removable, err := handler.autoremovablePackages(context.Background(), db)
+	require.NoError(t, err)
+
+	var names []string
+	for _, pkg := range removable {
+		names = append(names, pkg.Name)
+	}
+
+	assert.Contains(t, names, "ssl-cert")
+	assert.Contains(t, names, "firmware-linux-free")
+	assert.NotContains(t, names, "postfix")
+	assert.NotContains(t, names, "libssl3t64")
+}
+
+// TestGetAutoremovableFailed ensures unreadable extended states are reported instead of no autoremovable packages
+func TestGetAutoremovableFailed(t *testing.T) {
+	handler := newAutoremoveHandler("")
+	handler.sysCalls.(*mockSystemCalls).mockFiles["var/lib/apt/extended_states"] = &fstest.MapFile{Mode: fs.ModeDir}
+
+	_, err := handler.GetAutoremovable(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read apt extended states")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+
+	result := res.(*AllUpdatesResult)
+	assert.Nil(t, result.AutoremovableCount)
+	assert.Contains(t, result.SectionErrors, "autoremovable")
+
+	// The count is left out rather than reported as 0
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"autoremovable_count":null`)
+}
+
+// TestParseRelations ensures versions and architecture qualifiers are dropped from relation fields
+func TestParseRelations(t *testing.T) {
+	assert.Equal(t, [][]string{{"libc6"}, {"awk", "mawk"}, {"python3"}}, parseRelations("libc6 (>= 2.34), awk | mawk:any, python3:any(>= 3.12~)"))
+	assert.Nil(t, parseRelations(""))
+}
+
+// TestProtectedKernelReleases ensures the running, latest and previous kernels are protected without their flavour
+func TestProtectedKernelReleases(t *testing.T) {
+	kernels := []KernelPackage{{Release: "6.8.0-40-generic"}, {Release: "6.8.0-45-generic"}, {Release: "6.8.0-51-generic"}}
+
+	protected := protectedKernelReleases("6.8.0-40-generic", kernels)
+	assert.Equal(t, []string{"6.8.0-40", "6.8.0-45", "6.8.0-51"}, protected)
+
+	assert.True(t, isProtectedKernelPackage("linux-modules-extra-6.8.0-45-generic", protected))
+	assert.True(t, isProtectedKernelPackage("linux-headers-6.8.0-51", protected))
+	assert.False(t, isProtectedKernelPackage("linux-image-6.8.0-39-generic", protected))
+	assert.False(t, isProtectedKernelPackage("libfoo-6.8.0-45", protected))
+	assert.Equal(t, []string{"6.1.0-26"}, protectedKernelReleases("", []KernelPackage{{Release: "6.1.0-26-cloud-amd64"}}))
+}
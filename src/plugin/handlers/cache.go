@@ -0,0 +1,178 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.zabbix.com/sdk/errs"
+	"golang.zabbix.com/sdk/log"
+)
+
+// updateCache holds the last update snapshot produced by the background collector
+type updateCache struct {
+	mu        sync.RWMutex
+	result    *AllUpdatesResult
+	lastErr   error
+	updatedAt time.Time
+
+	readyOnce sync.Once
+	ready     chan struct{} // closed once the first refresh has finished
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartRefresh launches a background collector that checks for updates every
+// interval, giving each check at most timeout. Until StopRefresh is called,
+// metrics are served from its snapshot instead of running apt inline.
+func (h *Handler) StartRefresh(interval, timeout time.Duration, logger log.Logger) {
+	h.StopRefresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &updateCache{
+		ready:  make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	h.cache.Store(c)
+	h.logger.Store(&logger)
+
+	go c.run(ctx, h, interval, timeout, logger)
+}
+
+// StopRefresh stops the background collector and waits for it to exit.
+func (h *Handler) StopRefresh() {
+	c := h.cache.Swap(nil)
+	if c == nil {
+		return
+	}
+
+	c.cancel()
+	<-c.done
+}
+
+// snapshot returns the most recent update information. Without a running
+// collector the updates are checked inline.
+func (h *Handler) snapshot(ctx context.Context) (*AllUpdatesResult, error) {
+	c := h.cache.Load()
+	if c == nil {
+		return h.getAllUpdates(ctx)
+	}
+
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		return nil, errs.Wrap(ctx.Err(), "no update snapshot collected yet")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.result == nil {
+		return nil, errs.Wrap(c.lastErr, "failed to collect APT updates")
+	}
+
+	// Copy the snapshot so the age and error can be set per request
+	result := *c.result
+	result.SnapshotAgeSeconds = time.Since(c.updatedAt).Seconds()
+	if c.lastErr != nil {
+		result.LastError = c.lastErr.Error()
+	}
+
+	return &result, nil
+}
+
+// warnf logs a problem that does not fail the check. Without a running
+// collector there is no logger and the message is dropped.
+func (h *Handler) warnf(format string, args ...any) {
+	if logger := h.logger.Load(); logger != nil {
+		(*logger).Warningf(format, args...)
+	}
+}
+
+// run refreshes the snapshot immediately and then on every tick until ctx is cancelled
+func (c *updateCache) run(ctx context.Context, h *Handler, interval, timeout time.Duration, logger log.Logger) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Runs never overlap, ticks missed during a slow refresh are dropped
+		c.refresh(ctx, h, timeout, logger)
+		c.warnStale(interval, logger)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh runs a single update check and stores its result
+func (c *updateCache) refresh(ctx context.Context, h *Handler, timeout time.Duration, logger log.Logger) {
+	refreshCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	result, err := h.getAllUpdates(refreshCtx)
+
+	c.mu.Lock()
+	if err != nil {
+		// Keep serving the previous snapshot, flagged with the error
+		c.lastErr = err
+	} else {
+		c.result = result
+		c.lastErr = nil
+		c.updatedAt = time.Now()
+	}
+	c.mu.Unlock()
+
+	c.readyOnce.Do(func() { close(c.ready) })
+
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errf("failed to refresh APT updates: %s", err.Error())
+		}
+
+		return
+	}
+
+	logger.Debugf("refreshed APT updates in %s", time.Since(startTime))
+}
+
+// warnStale logs when the served snapshot is older than the refresh interval
+func (c *updateCache) warnStale(interval time.Duration, logger log.Logger) {
+	c.mu.RLock()
+	updatedAt := c.updatedAt
+	c.mu.RUnlock()
+
+	if updatedAt.IsZero() {
+		return
+	}
+
+	if age := time.Since(updatedAt); age > interval {
+		logger.Warningf("APT updates snapshot is %s old, older than the refresh interval of %s",
+			age.Truncate(time.Second), interval)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRefreshServesSnapshot ensures metrics are served from the background snapshot
+func TestRefreshServesSnapshot(t *testing.T) {
+	handler := &Handler{
+		sysCalls: newMockSystemCalls("libssl3/noble-updates 3.0.13-0ubuntu3.5]\ncurl/noble-updates 8.5.0-2ubuntu10.6]\n", nil),
+	}
+
+	handler.StartRefresh(time.Hour, time.Minute, nopLogger{})
+	defer handler.StopRefresh()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := handler.GetAllUpdates(ctx, nil)
+	assert.NoError(t, err)
+
+	result := res.(*AllUpdatesResult)
+	assert.Equal(t, 2, result.AllUpdatesCount)
+	assert.Empty(t, result.LastError)
+	assert.GreaterOrEqual(t, result.SnapshotAgeSeconds, 0.0)
+
+	count, err := handler.CheckUpdateCount(ctx, map[string]string{"type": "all", "phased": "exclude-phased"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+// TestRefreshReportsError ensures a failed first refresh is returned as an error
+func TestRefreshReportsError(t *testing.T) {
+	handler := &Handler{
+		sysCalls: failingSystemCalls{err: errors.New("apt-get: not found")},
+	}
+
+	handler.StartRefresh(time.Hour, time.Minute, nopLogger{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := handler.GetAllUpdates(ctx, nil)
+	assert.ErrorContains(t, err, "apt-get: not found")
+
+	handler.StopRefresh()
+	assert.Nil(t, handler.cache.Load())
+}
+
+// TestRefreshTimeout ensures a background check is cancelled after the refresh timeout
+func TestRefreshTimeout(t *testing.T) {
+	handler := &Handler{
+		sysCalls: blockingSystemCalls{},
+	}
+
+	handler.StartRefresh(time.Hour, 50*time.Millisecond, nopLogger{})
+	defer handler.StopRefresh()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := handler.GetAllUpdates(ctx, nil)
+	assert.ErrorContains(t, err, context.DeadlineExceeded.Error())
+}
+
+// TestWarnStale ensures a snapshot older than the refresh interval is logged
+func TestWarnStale(t *testing.T) {
+	logger := &recordingLogger{}
+	c := &updateCache{}
+
+	c.warnStale(time.Minute, logger)
+	assert.Empty(t, logger.warnings, "no snapshot collected yet")
+
+	c.updatedAt = time.Now().Add(-30 * time.Second)
+	c.warnStale(time.Minute, logger)
+	assert.Empty(t, logger.warnings)
+
+	c.updatedAt = time.Now().Add(-2 * time.Minute)
+	c.warnStale(time.Minute, logger)
+	if assert.Len(t, logger.warnings, 1) {
+		assert.Contains(t, logger.warnings[0], "older than the refresh interval of 1m0s")
+	}
+}
+
+// blockingSystemCalls implements systemCalls interface with every command
+// running until its context is done
+type blockingSystemCalls struct {
+	mockFiles
+}
+
+func (blockingSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+// recordingLogger implements log.Logger keeping the warnings
+type recordingLogger struct {
+	nopLogger
+	warnings []string
+}
+
+func (l *recordingLogger) Warningf(format string, args ...any) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+// failingSystemCalls implements systemCalls interface with every command failing
+type failingSystemCalls struct {
+	mockFiles
+	err error
+}
+
+func (f failingSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return nil, f.err
+}
+
+// nopLogger implements log.Logger discarding all messages
+type nopLogger struct{}
+
+func (nopLogger) Tracef(string, ...any)   {}
+func (nopLogger) Debugf(string, ...any)   {}
+func (nopLogger) Warningf(string, ...any) {}
+func (nopLogger) Infof(string, ...any)    {}
+func (nopLogger) Errf(string, ...any)     {}
+func (nopLogger) Critf(string, ...any)    {}
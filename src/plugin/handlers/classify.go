@@ -0,0 +1,348 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// releaseFields are the properties of the release a package version comes from,
+// as apt-cache policy prints them (o=, l=, a=, n=, c=) plus the repository host and path
+type releaseFields struct {
+	Origin    string
+	Label     string
+	Archive   string
+	Codename  string
+	Component string
+	Site      string
+	Path      string // URI path of the repository, e.g. ondrej/php/ubuntu
+}
+
+// securityRule decides whether a release is a security archive
+type securityRule struct {
+	Name  string
+	Match func(release releaseFields) bool
+}
+
+// securityRules are tried in order, the first matching rule is reported.
+// Suites are matched by their -security suffix, so every Debian and Ubuntu
+// codename is covered without listing them.
+//
+//nolint:gochecknoglobals // rule table.
+var securityRules = []securityRule{
+	{
+		// Debian: <codename>-security since bullseye, <codename>/updates before,
+		// both labelled Debian-Security
+		Name: "debian-security",
+		Match: func(r releaseFields) bool {
+			return r.Origin == "Debian" && (r.Label == "Debian-Security" || isSecuritySuite(r))
+		},
+	},
+	{
+		// Ubuntu: <codename>-security pocket
+		Name: "ubuntu-security",
+		Match: func(r releaseFields) bool {
+			return r.Origin == "Ubuntu" && isSecuritySuite(r)
+		},
+	},
+	{
+		// Ubuntu Pro: UbuntuESM (<codename>-infra-security) and UbuntuESMApps (<codename>-apps-security)
+		Name: "ubuntu-esm",
+		Match: func(r releaseFields) bool {
+			return strings.HasPrefix(r.Origin, "UbuntuESM") && isSecuritySuite(r)
+		},
+	},
+	{
+		// Derivatives and third-party repositories following the same suite naming
+		Name:  "security-suite",
+		Match: isSecuritySuite,
+	},
+	{
+		// Labels following Debian-Security, not any label mentioning security
+		Name: "security-label",
+		Match: func(r releaseFields) bool {
+			return strings.HasSuffix(r.Label, "-Security")
+		},
+	},
+	{
+		// Only used when no release fields are known, e.g. security.ubuntu.com
+		Name: "security-host",
+		Match: func(r releaseFields) bool {
+			return r.Origin == "" && r.Label == "" && r.Archive == "" && strings.HasPrefix(r.Site, "security.")
+		},
+	},
+}
+
+// isSecuritySuite reports whether the release's archive or codename is a security suite
+func isSecuritySuite(r releaseFields) bool {
+	return strings.HasSuffix(r.Archive, "-security") || strings.HasSuffix(r.Codename, "-security")
+}
+
+// matchSecurityRule returns the name of the first security rule matching any of
+// the releases a version is available from, or an empty string
+func matchSecurityRule(releases []releaseFields) string {
+	for _, rule := range securityRules {
+		for _, release := range releases {
+			if rule.Match(release) {
+				return rule.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+// Pockets of a distribution release, see releasePocket
+const (
+	pocketSecurity  = "security"
+	pocketUpdates   = "updates"
+	pocketRelease   = "release" // The release suite itself, e.g. Debian stable with its point releases
+	pocketBackports = "backports"
+	pocketProposed  = "proposed"
+)
+
+// pocketOrder ranks the pockets by significance, most significant first
+//
+//nolint:gochecknoglobals // lookup table.
+var pocketOrder = []string{pocketSecurity, pocketUpdates, pocketRelease, pocketBackports, pocketProposed}
+
+// distributionOrigins are the origins whose suites follow the distribution pocket layout.
+// Third-party repositories name their suites freely, e.g. Docker "noble" or Grafana "stable".
+//
+//nolint:gochecknoglobals // lookup table.
+var distributionOrigins = []string{
+	"Debian", "Debian Backports", "Ubuntu", "UbuntuESM", "UbuntuESMApps", "Devuan", "Raspbian",
+}
+
+// isDistributionRelease reports whether a release belongs to the distribution itself.
+// Inst lines only carry the label, which the distributions set to their origin.
+func isDistributionRelease(release releaseFields) bool {
+	origin := release.Origin
+	if origin == "" {
+		origin = release.Label
+	}
+
+	return slices.Contains(distributionOrigins, origin)
+}
+
+// releasePocket returns the pocket of a release from its suite name:
+// noble-security, bookworm-updates, bookworm-backports, stable-proposed-updates or plain noble/stable.
+// Apart from security suites, only distribution releases have a pocket.
+func releasePocket(release releaseFields) string {
+	if matchSecurityRule([]releaseFields{release}) != "" {
+		return pocketSecurity
+	}
+
+	if !isDistributionRelease(release) {
+		return ""
+	}
+
+	suite := release.Archive
+	if suite == "" {
+		suite = release.Codename
+	}
+
+	switch {
+	case suite == "":
+		return ""
+	case strings.HasSuffix(suite, "-proposed-updates"), strings.HasSuffix(suite, "-proposed"):
+		return pocketProposed
+	case strings.HasSuffix(suite, "-backports"), strings.HasSuffix(suite, "-backports-sloppy"):
+		return pocketBackports
+	case strings.HasSuffix(suite, "-updates"):
+		return pocketUpdates
+	}
+
+	return pocketRelease
+}
+
+// updatePocket returns the most significant pocket of the releases a version is available from
+func updatePocket(releases []releaseFields) string {
+	best := len(pocketOrder)
+	for _, release := range releases {
+		if i := slices.Index(pocketOrder, releasePocket(release)); i >= 0 && i < best {
+			best = i
+		}
+	}
+
+	if best == len(pocketOrder) {
+		return ""
+	}
+
+	return pocketOrder[best]
+}
+
+// classifyReleases returns the update categories of a version available from
+// the given releases and the security rule that matched, if any.
+// Recommended updates come from the -updates pocket or the release suite itself
+// (point releases) of main/restricted, optional updates come from -backports,
+// -proposed, universe or multiverse. Versions only published in a security
+// pocket are security updates only. Third-party releases are left to the rules.
+func classifyReleases(releases []releaseFields) (map[UpdateType]bool, string) {
+	categories := map[UpdateType]bool{
+		UpdateTypeAll: true,
+	}
+
+	rule := matchSecurityRule(releases)
+	categories[UpdateTypeSecurity] = rule != ""
+
+	for _, release := range releases {
+		if isDistributionRelease(release) && (release.Component == "universe" || release.Component == "multiverse") {
+			categories[UpdateTypeOptional] = true
+		}
+
+		switch releasePocket(release) {
+		case pocketBackports, pocketProposed:
+			categories[UpdateTypeOptional] = true
+		case pocketUpdates, pocketRelease:
+			categories[UpdateTypeRecommended] = true
+		}
+	}
+
+	if categories[UpdateTypeOptional] {
+		categories[UpdateTypeRecommended] = false
+	}
+
+	return categories, rule
+}
+
+// indexedReleases returns the releases of the repository index entries of a version
+func indexedReleases(entries []indexedPackage) []releaseFields {
+	releases := make([]releaseFields, 0, len(entries))
+	for _, entry := range entries {
+		releases = append(releases, entry.release())
+	}
+
+	return releases
+}
+
+// instReleases returns the releases of the repositories printed in an Inst line
+func instReleases(sources []InstSource) []releaseFields {
+	releases := make([]releaseFields, 0, len(sources))
+	for _, source := range sources {
+		releases = append(releases, releaseFields{Label: source.Label, Archive: source.Suite})
+	}
+
+	return releases
+}
+
+// classifyUpdates returns the update categories of every package, keyed by name,
+// and records the matching security rule in the updates. Only the releases the
+// target version is available from are considered. Target versions are looked up
+// in the repository indexes first; the remaining packages are classified with a
+// single batched apt-cache policy call. The repositories printed in the Inst lines
+// carry neither origin, component nor section and are only used for packages
+// neither source knows. The configured rules are applied on top of the built-in
+// classification.
+func (h *Handler) classifyUpdates(ctx context.Context, updates []UpdateInfo) map[string]map[UpdateType]bool {
+	classified := make(map[string]map[UpdateType]bool, len(updates))
+
+	names := make(map[string]bool, len(updates))
+	for _, pkg := range updates {
+		name, _, _ := strings.Cut(pkg.Name, ":")
+		names[name] = true
+	}
+
+	idx, err := h.loadPackageIndex(ctx, names)
+	if err != nil {
+		// Fall back to apt-cache policy for every package
+		idx = nil
+	}
+
+	var missing []int
+	for i, pkg := range updates {
+		entries := idx.versions(pkg.Name, pkg.Target)
+		if len(entries) == 0 {
+			missing = append(missing, i)
+
+			continue
+		}
+
+		classified[pkg.Name] = h.classifyUpdate(&updates[i], entries[0].Section, indexedReleases(entries))
+	}
+
+	if len(missing) == 0 {
+		return classified
+	}
+
+	missingNames := make([]string, 0, len(missing))
+	for _, i := range missing {
+		missingNames = append(missingNames, updates[i].Name)
+	}
+
+	policies, policyErr := h.aptCachePolicy(ctx, missingNames)
+
+	var sourceReleases map[string]releaseFields
+	if policyErr == nil {
+		// Without release fields the sources are classified by suite and host only
+		sourceReleases, err = h.aptSourceReleases(ctx)
+		if err != nil {
+			sourceReleases = nil
+		}
+	}
+
+	for _, i := range missing {
+		var releases []releaseFields
+
+		policy, ok := policies[updates[i].Name]
+		if !ok {
+			// apt-cache omits the native architecture from the package header
+			name, _, _ := strings.Cut(updates[i].Name, ":")
+			policy, ok = policies[name]
+		}
+
+		// apt-cache prints nothing for packages it does not know
+		if ok {
+			releases = policy.targetReleases(updates[i].Target, sourceReleases)
+		}
+
+		if len(releases) == 0 && len(updates[i].Sources) > 0 {
+			// Last resort, the Inst line names the suites only
+			releases = instReleases(updates[i].Sources)
+		}
+
+		if len(releases) == 0 && policyErr != nil {
+			// If we can't determine the types, the packages only count as updates
+			classified[updates[i].Name] = map[UpdateType]bool{UpdateTypeAll: true}
+			h.applyRules(&updates[i], classified[updates[i].Name], "", nil)
+
+			continue
+		}
+
+		// apt-cache policy does not show sections, rules matching them only apply to indexed versions
+		classified[updates[i].Name] = h.classifyUpdate(&updates[i], "", releases)
+	}
+
+	return classified
+}
+
+// classifyUpdate classifies an update by the releases its target version is available
+// from and records the security rule, pocket and repository in the update
+func (h *Handler) classifyUpdate(update *UpdateInfo, section string, releases []releaseFields) map[UpdateType]bool {
+	categories, rule := classifyReleases(releases)
+	update.SecurityRule = rule
+	update.Pocket = updatePocket(releases)
+	update.Repository = updateRepository(releases)
+	update.releases = releases
+	h.applyRules(update, categories, section, releases)
+
+	return categories
+}
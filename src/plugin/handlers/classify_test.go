@@ -0,0 +1,327 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSourcesPolicyOutput = `Package files:
+ 100 /var/lib/dpkg/status
+     release a=now
+ 500 https://ppa.launchpadcontent.net/ondrej/php/ubuntu noble/main amd64 Packages
+     release v=24.04,o=LP-PPA-ondrej-php,a=noble,n=noble,l=***** The main PPA for supported PHP versions *****,c=main,b=amd64
+     origin ppa.launchpadcontent.net
+ 500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+     release v=24.04,o=Ubuntu,a=noble-security,n=noble,l=Ubuntu,c=main,b=amd64
+     origin security.ubuntu.com
+ 500 http://archive.ubuntu.com/ubuntu noble-updates/universe amd64 Packages
+     release v=24.04,o=Ubuntu,a=noble-updates,n=noble,l=Ubuntu,c=universe,b=amd64
+     origin archive.ubuntu.com
+ 500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+     release v=24.04,o=Ubuntu,a=noble-updates,n=noble,l=Ubuntu,c=main,b=amd64
+     origin archive.ubuntu.com
+ 500 http://mirror.example.com/ubuntu noble-security/main amd64 Packages
+Pinned packages:
+     openssl -> 3.0.13-0ubuntu3.5 with priority 1001
+`
+
+// TestSecurityRules ensures security archives are recognised by their release fields
+func TestSecurityRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		release releaseFields
+		rule    string
+	}{
+		{
+			name:    "debian bookworm-security",
+			release: releaseFields{Origin: "Debian", Label: "Debian-Security", Archive: "stable-security", Codename: "bookworm-security", Component: "main"},
+			rule:    "debian-security",
+		},
+		{
+			name:    "debian buster/updates",
+			release: releaseFields{Origin: "Debian", Label: "Debian-Security", Archive: "oldoldstable", Codename: "buster", Component: "updates/main"},
+			rule:    "debian-security",
+		},
+		{
+			name:    "debian point release",
+			release: releaseFields{Origin: "Debian", Label: "Debian", Archive: "stable", Codename: "bookworm", Component: "main"},
+		},
+		{
+			name:    "ubuntu jammy-security",
+			release: releaseFields{Origin: "Ubuntu", Label: "Ubuntu", Archive: "jammy-security", Codename: "jammy", Component: "main"},
+			rule:    "ubuntu-security",
+		},
+		{
+			name:    "ubuntu noble-security universe",
+			release: releaseFields{Origin: "Ubuntu", Label: "Ubuntu", Archive: "noble-security", Codename: "noble", Component: "universe"},
+			rule:    "ubuntu-security",
+		},
+		{
+			name:    "ubuntu noble-updates",
+			release: releaseFields{Origin: "Ubuntu", Label: "Ubuntu", Archive: "noble-updates", Codename: "noble", Component: "main"},
+		},
+		{
+			name:    "ubuntu pro esm-infra",
+			release: releaseFields{Origin: "UbuntuESM", Label: "Ubuntu", Archive: "jammy-infra-security", Codename: "jammy", Component: "main"},
+			rule:    "ubuntu-esm",
+		},
+		{
+			name:    "ubuntu pro esm-apps",
+			release: releaseFields{Origin: "UbuntuESMApps", Label: "Ubuntu", Archive: "jammy-apps-security", Codename: "jammy", Component: "main"},
+			rule:    "ubuntu-esm",
+		},
+		{
+			name:    "ubuntu pro esm-apps updates",
+			release: releaseFields{Origin: "UbuntuESMApps", Label: "Ubuntu", Archive: "jammy-apps-updates", Codename: "jammy", Component: "main"},
+		},
+		{
+			name:    "ppa with security in its name",
+			release: releaseFields{Origin: "LP-PPA-security-tools", Label: "Tools", Archive: "noble", Codename: "noble", Component: "main"},
+		},
+		{
+			name:    "derivative security suite",
+			release: releaseFields{Origin: "Raspbian", Archive: "bookworm-security", Codename: "bookworm"},
+			rule:    "security-suite",
+		},
+		{
+			name:    "unknown release from a security host",
+			release: releaseFields{Site: "security.debian.org"},
+			rule:    "security-host",
+		},
+		{
+			name:    "derivative security label",
+			release: releaseFields{Origin: "Devuan", Label: "Devuan-Security", Archive: "daedalus", Codename: "daedalus"},
+			rule:    "security-label",
+		},
+		{
+			name:    "third-party label mentioning security",
+			release: releaseFields{Origin: "Wazuh", Label: "Wazuh Security Platform", Archive: "stable", Codename: "stable"},
+		},
+		{
+			name:    "third-party repository on a security host",
+			release: releaseFields{Origin: "Example", Label: "Example", Archive: "stable", Site: "security.example.com"},
+		},
+		{
+			name:    "updates suite on a security host",
+			release: releaseFields{Archive: "noble-updates", Site: "security.ubuntu.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categories, rule := classifyReleases([]releaseFields{tt.release})
+			assert.Equal(t, tt.rule, rule)
+			assert.Equal(t, tt.rule != "", categories[UpdateTypeSecurity])
+			assert.True(t, categories[UpdateTypeAll])
+		})
+	}
+}
+
+// TestClassifyPolicyTargetVersion ensures only the sources of the target version are considered
+func TestClassifyPolicyTargetVersion(t *testing.T) {
+	policy := &packagePolicy{
+		Installed: "1.0-1",
+		Candidate: "1.1-1",
+		Versions: []policyVersion{
+			{Version: "1.1-1", Sources: []policySource{
+				{URI: "http://archive.ubuntu.com/ubuntu", Suite: "noble-updates", Component: "main", Architecture: "amd64"},
+			}},
+			{Version: "1.0-1", Installed: true, Sources: []policySource{
+				{URI: "http://security.ubuntu.com/ubuntu", Suite: "noble-security", Component: "main", Architecture: "amd64"},
+				{URI: "/var/lib/dpkg/status"},
+			}},
+		},
+	}
+
+	sourceReleases := parseSourceReleases(testSourcesPolicyOutput)
+
+	categories, rule := classifyReleases(policy.targetReleases("1.1-1", sourceReleases))
+	assert.False(t, categories[UpdateTypeSecurity])
+	assert.Empty(t, rule)
+
+	// Unknown targets fall back to the candidate
+	categories, _ = classifyReleases(policy.targetReleases("2.0-1", sourceReleases))
+	assert.False(t, categories[UpdateTypeSecurity])
+
+	categories, rule = classifyReleases(policy.targetReleases("1.0-1", sourceReleases))
+	assert.True(t, categories[UpdateTypeSecurity])
+	assert.Equal(t, "ubuntu-security", rule)
+}
+
+// TestParseSourceReleases ensures the release fields of the package files are parsed
+func TestParseSourceReleases(t *testing.T) {
+	releases := parseSourceReleases(testSourcesPolicyOutput)
+	require.Len(t, releases, 5)
+
+	assert.Equal(t, releaseFields{
+		Origin:    "Ubuntu",
+		Label:     "Ubuntu",
+		Archive:   "noble-security",
+		Codename:  "noble",
+		Component: "main",
+		Site:      "security.ubuntu.com",
+		Path:      "ubuntu",
+	}, releases["http://security.ubuntu.com/ubuntu noble-security/main amd64"])
+
+	ppa := releases["https://ppa.launchpadcontent.net/ondrej/php/ubuntu noble/main amd64"]
+	assert.Equal(t, "LP-PPA-ondrej-php", ppa.Origin)
+
+	// Package files without release fields keep the suite of the source line
+	assert.Equal(t, releaseFields{
+		Archive:   "noble-security",
+		Component: "main",
+		Site:      "mirror.example.com",
+		Path:      "ubuntu",
+	}, releases["http://mirror.example.com/ubuntu noble-security/main amd64"])
+}
+
+// TestClassifyUpdatesSecurityRule ensures the matching security rule is recorded in the updates
+func TestClassifyUpdatesSecurityRule(t *testing.T) {
+	handler := &Handler{sysCalls: &policySystemCalls{}}
+
+	updates := []UpdateInfo{
+		{Name: "openssl", Target: "3.0.13-0ubuntu3.5"},
+		{Name: "htop", Target: "3.3.0-4build1"},
+	}
+	classified := handler.classifyUpdates(context.Background(), updates)
+
+	assert.True(t, classified["openssl"][UpdateTypeSecurity])
+	assert.Equal(t, "ubuntu-security", updates[0].SecurityRule)
+	assert.Equal(t, pocketSecurity, updates[0].Pocket)
+	assert.False(t, classified["htop"][UpdateTypeSecurity])
+	assert.Empty(t, updates[1].SecurityRule)
+	assert.Equal(t, pocketUpdates, updates[1].Pocket)
+}
+
+// policySystemCalls returns the package policies for apt-cache policy with packages
+// and the package files without
+type policySystemCalls struct {
+	mockFiles
+}
+
+func (p *policySystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if strings.Join(args, " ") == "LC_ALL=C LANG=C apt-cache policy" {
+		return []byte(testSourcesPolicyOutput), nil
+	}
+
+	return []byte(testPolicyOutput), nil
+}
+
+// TestClassifyPockets ensures recommended and optional updates are told apart by pocket and component
+func TestClassifyPockets(t *testing.T) {
+	tests := []struct {
+		name        string
+		releases    []releaseFields
+		pocket      string
+		recommended bool
+		optional    bool
+		security    bool
+	}{
+		{
+			name: "ubuntu security fix copied to updates",
+			releases: []releaseFields{
+				{Origin: "Ubuntu", Archive: "noble-updates", Codename: "noble", Component: "main"},
+				{Origin: "Ubuntu", Archive: "noble-security", Codename: "noble", Component: "main"},
+			},
+			pocket: pocketSecurity, recommended: true, security: true,
+		},
+		{
+			name:     "ubuntu security pocket only",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-security", Codename: "noble", Component: "main"}},
+			pocket:   pocketSecurity, security: true,
+		},
+		{
+			name:     "ubuntu updates",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-updates", Codename: "noble", Component: "restricted"}},
+			pocket:   pocketUpdates, recommended: true,
+		},
+		{
+			name:     "ubuntu updates universe",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-updates", Codename: "noble", Component: "universe"}},
+			pocket:   pocketUpdates, optional: true,
+		},
+		{
+			name:     "ubuntu backports",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-backports", Codename: "noble", Component: "main"}},
+			pocket:   pocketBackports, optional: true,
+		},
+		{
+			name:     "ubuntu proposed",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-proposed", Codename: "noble", Component: "main"}},
+			pocket:   pocketProposed, optional: true,
+		},
+		{
+			name:     "debian point release",
+			releases: []releaseFields{{Origin: "Debian", Archive: "stable", Codename: "bookworm", Component: "main"}},
+			pocket:   pocketRelease, recommended: true,
+		},
+		{
+			name:     "debian stable-updates",
+			releases: []releaseFields{{Origin: "Debian", Archive: "stable-updates", Codename: "bookworm-updates", Component: "main"}},
+			pocket:   pocketUpdates, recommended: true,
+		},
+		{
+			name:     "debian proposed-updates",
+			releases: []releaseFields{{Origin: "Debian", Archive: "stable-proposed-updates", Codename: "bookworm-proposed-updates", Component: "main"}},
+			pocket:   pocketProposed, optional: true,
+		},
+		{
+			name:     "debian backports",
+			releases: []releaseFields{{Origin: "Debian Backports", Archive: "stable-backports", Codename: "bookworm-backports", Component: "main"}},
+			pocket:   pocketBackports, optional: true,
+		},
+		{
+			name:     "docker plain suite",
+			releases: []releaseFields{{Origin: "Docker", Label: "Docker CE", Archive: "noble", Codename: "noble", Component: "stable"}},
+		},
+		{
+			name:     "pgdg suite",
+			releases: []releaseFields{{Origin: "apt.postgresql.org", Label: "PostgreSQL for Debian/Ubuntu repository", Archive: "noble-pgdg", Component: "main"}},
+		},
+		{
+			name:     "grafana stable",
+			releases: []releaseFields{{Origin: "Grafana", Label: "Grafana", Archive: "stable", Component: "main"}},
+		},
+		{
+			name:     "ubuntu suite from an inst line",
+			releases: []releaseFields{{Label: "Ubuntu", Archive: "noble-updates"}},
+			pocket:   pocketUpdates, recommended: true,
+		},
+		{
+			name:     "unknown release",
+			releases: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			categories, _ := classifyReleases(tt.releases)
+			assert.Equal(t, tt.pocket, updatePocket(tt.releases))
+			assert.Equal(t, tt.recommended, categories[UpdateTypeRecommended])
+			assert.Equal(t, tt.optional, categories[UpdateTypeOptional])
+			assert.Equal(t, tt.security, categories[UpdateTypeSecurity])
+		})
+	}
+}
@@ -0,0 +1,174 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// conffilesDir is scanned for configuration file leftovers
+	conffilesDir = "/etc"
+	// ucfRegistryFile maps the configuration files managed by ucf to their packages
+	ucfRegistryFile = "/var/lib/ucf/registry"
+	// sectionConffiles names the configuration file scan in AllUpdatesResult.SectionErrors
+	sectionConffiles = "conffile_leftovers"
+)
+
+// conffileSuffixes are the leftovers dpkg and ucf create when a configuration file
+// was changed locally and the package ships a new version of it
+//
+//nolint:gochecknoglobals // lookup table.
+var conffileSuffixes = []string{".dpkg-dist", ".dpkg-new", ".dpkg-old", ".ucf-dist", ".ucf-new"}
+
+// ConffileLeftover is a configuration file version left next to the one in use
+type ConffileLeftover struct {
+	Path     string `json:"path"`
+	Conffile string `json:"conffile"` // Configuration file in use
+	Kind     string `json:"kind"`     // dpkg-dist, dpkg-new, dpkg-old, ucf-dist or ucf-new
+	Package  string `json:"package"`  // Package owning the configuration file, empty if unknown
+	Modified int64  `json:"modified"` // Unix timestamp of the leftover
+}
+
+// ConffileStatus lists the configuration file leftovers found in conffilesDir
+type ConffileStatus struct {
+	Count         int                `json:"count"`
+	PackagesCount int                `json:"packages_count"`
+	Packages      []string           `json:"packages"` // Owners of the leftovers, sorted
+	Files         []ConffileLeftover `json:"files"`
+}
+
+// GetConffileLeftovers returns the pending configuration file conflicts and their packages
+func (h *Handler) GetConffileLeftovers(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.ConffileLeftovers == nil {
+		return nil, result.sectionError(sectionConffiles, "failed to scan for configuration file leftovers")
+	}
+
+	return result.ConffileLeftovers, nil
+}
+
+// conffileLeftovers scans conffilesDir for leftovers and maps them to their packages
+// through the conffiles of the dpkg database and the ucf registry
+func (h *Handler) conffileLeftovers(ctx context.Context, db dpkgDatabase) (*ConffileStatus, error) {
+	owners := make(map[string]string)
+	for _, entries := range db {
+		for _, pkg := range entries {
+			for _, conffile := range pkg.conffiles {
+				owners[conffile] = pkg.Name
+			}
+		}
+	}
+
+	// Files managed by ucf are no dpkg conffiles
+	data, err := h.readFile(ucfRegistryFile)
+	if err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) == 2 {
+				if _, ok := owners[fields[1]]; !ok {
+					owners[fields[1]] = fields[0]
+				}
+			}
+		}
+	}
+
+	status := &ConffileStatus{Packages: []string{}, Files: []ConffileLeftover{}}
+	packages := make(map[string]bool)
+
+	dirs := []string{conffilesDir}
+	for len(dirs) > 0 {
+		if ctx.Err() != nil {
+			return nil, errs.Wrap(ctx.Err(), "configuration file scan interrupted")
+		}
+
+		dir := dirs[len(dirs)-1]
+		dirs = dirs[:len(dirs)-1]
+
+		entries, err := h.sysCalls.readDir(dir)
+		if err != nil {
+			if dir == conffilesDir {
+				return nil, errs.Wrapf(err, "failed to list %s", dir)
+			}
+
+			// Directories only root can read
+			continue
+		}
+
+		for _, entry := range entries {
+			name := path.Join(dir, entry.Name())
+
+			// Symbolic links to directories are not followed
+			if entry.IsDir() {
+				dirs = append(dirs, name)
+
+				continue
+			}
+
+			for _, suffix := range conffileSuffixes {
+				conffile, ok := strings.CutSuffix(name, suffix)
+				if !ok {
+					continue
+				}
+
+				leftover := ConffileLeftover{
+					Path:     name,
+					Conffile: conffile,
+					Kind:     strings.TrimPrefix(suffix, "."),
+					Package:  owners[conffile],
+				}
+
+				if info, err := entry.Info(); err == nil {
+					leftover.Modified = info.ModTime().Unix()
+				}
+
+				status.Files = append(status.Files, leftover)
+
+				if leftover.Package != "" && !packages[leftover.Package] {
+					packages[leftover.Package] = true
+					status.Packages = append(status.Packages, leftover.Package)
+				}
+
+				break
+			}
+		}
+	}
+
+	sort.Slice(status.Files, func(i, j int) bool {
+		return status.Files[i].Path < status.Files[j].Path
+	})
+	sort.Strings(status.Packages)
+
+	status.Count = len(status.Files)
+	status.PackagesCount = len(status.Packages)
+
+	return status, nil
+}
@@ -0,0 +1,108 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConffileLeftovers ensures leftovers in /etc are found and mapped to their packages
+// through the dpkg conffiles and the ucf registry
+func TestConffileLeftovers(t *testing.T) {
+	modified := time.Date(2026, 10, 15, 6, 25, 30, 0, time.UTC)
+
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status":                       {Data: []byte(testDpkgStatus)},
+		"var/lib/ucf/registry":                      {Data: []byte("openssh-server\t/etc/ssh/sshd_config\n")},
+		"etc/nginx/nginx.conf":                      {Data: []byte{}},
+		"etc/nginx/nginx.conf.dpkg-dist":            {Data: []byte{}, ModTime: modified},
+		"etc/ssh/sshd_config":                       {Data: []byte{}},
+		"etc/ssh/sshd_config.ucf-dist":              {Data: []byte{}, ModTime: modified},
+		"etc/default/local-tool.dpkg-old":           {Data: []byte{}, ModTime: modified},
+		"etc/apt/apt.conf.d/50unattended-upgrades":  {Data: []byte{}},
+		"etc/nginx/sites-enabled/default.dpkg-save": {Data: []byte{}},
+	}}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	status, err := handler.conffileLeftovers(context.Background(), db)
+	require.NoError(t, err)
+
+	assert.Equal(t, &ConffileStatus{
+		Count:         3,
+		PackagesCount: 2,
+		Packages:      []string{"nginx", "openssh-server"},
+		Files: []ConffileLeftover{
+			{
+				Path:     "/etc/default/local-tool.dpkg-old",
+				Conffile: "/etc/default/local-tool",
+				Kind:     "dpkg-old",
+				Modified: modified.Unix(),
+			},
+			{
+				Path:     "/etc/nginx/nginx.conf.dpkg-dist",
+				Conffile: "/etc/nginx/nginx.conf",
+				Kind:     "dpkg-dist",
+				Package:  "nginx",
+				Modified: modified.Unix(),
+			},
+			{
+				Path:     "/etc/ssh/sshd_config.ucf-dist",
+				Conffile: "/etc/ssh/sshd_config",
+				Kind:     "ucf-dist",
+				Package:  "openssh-server",
+				Modified: modified.Unix(),
+			},
+		},
+	}, status)
+}
+
+// TestConffileLeftoversNone ensures an empty result is reported without leftovers
+func TestConffileLeftoversNone(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles(fstest.MapFS{
+		"etc/hostname": {Data: []byte("host\n")},
+	})}}
+
+	status, err := handler.conffileLeftovers(context.Background(), dpkgDatabase{})
+	require.NoError(t, err)
+	assert.Equal(t, &ConffileStatus{Packages: []string{}, Files: []ConffileLeftover{}}, status)
+}
+
+// TestGetConffileLeftoversFailed ensures the cause of a failed scan is returned and kept in the snapshot
+func TestGetConffileLeftoversFailed(t *testing.T) {
+	// No /etc to scan
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status": {Data: []byte(testDpkgStatus)},
+	}}}
+
+	_, err := handler.GetConffileLeftovers(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list /etc")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.(*AllUpdatesResult).SectionErrors["conffile_leftovers"], "failed to list /etc")
+}
@@ -0,0 +1,137 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// maxDeb822LineSize bounds a single line of a control file; long Description
+// and Conffiles fields can exceed the bufio.Scanner default
+const maxDeb822LineSize = 16 * 1024 * 1024
+
+// deb822Stanza is a single paragraph of a deb822 control file, keyed by field name
+type deb822Stanza map[string]string
+
+// parseDeb822 reads the paragraphs of a deb822 control file (Packages, Release,
+// dpkg status) and calls fn for each of them. Continuation lines are appended to
+// the field value separated by newlines, with their leading whitespace removed.
+func parseDeb822(r io.Reader, fn func(stanza deb822Stanza) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxDeb822LineSize)
+
+	stanza := deb822Stanza{}
+	lastKey := ""
+
+	flush := func() error {
+		if len(stanza) == 0 {
+			return nil
+		}
+
+		err := fn(stanza)
+		stanza = deb822Stanza{}
+		lastKey = ""
+
+		return err
+	}
+
+	for sc.Scan() {
+		line := sc.Text()
+
+		if strings.TrimSpace(line) == "" {
+			err := flush()
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// Comments are only allowed at the start of a line (e.g. in .sources files)
+		if line[0] == '#' {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastKey != "" {
+				stanza[lastKey] += "\n" + strings.TrimSpace(line)
+			}
+
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		lastKey = strings.TrimSpace(key)
+		stanza[lastKey] = strings.TrimSpace(value)
+	}
+
+	if err := sc.Err(); err != nil {
+		return errs.Wrap(err, "failed to read control file")
+	}
+
+	return flush()
+}
+
+// stripPGPSignature returns the signed content of a clearsigned document such as
+// InRelease. Documents without a signature are returned unchanged.
+func stripPGPSignature(data string) string {
+	const (
+		signedHeader    = "-----BEGIN PGP SIGNED MESSAGE-----"
+		signatureHeader = "-----BEGIN PGP SIGNATURE-----"
+	)
+
+	if !strings.HasPrefix(strings.TrimSpace(data), signedHeader) {
+		return data
+	}
+
+	var (
+		b        strings.Builder
+		inHeader = true
+	)
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		switch {
+		case inHeader:
+			// Armor headers (Hash: ...) end with the first empty line
+			if line == "" {
+				inHeader = false
+			}
+
+			continue
+		case line == signatureHeader:
+			return b.String()
+		}
+
+		// Lines starting with a dash are dash-escaped in the signed text
+		b.WriteString(strings.TrimPrefix(line, "- "))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
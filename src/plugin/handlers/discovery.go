@@ -0,0 +1,117 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+
+	"zabbix-agent2-apt-updates/src/plugin/params"
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// categoryPhased is the discovery category of updates held back by phased rollout
+	categoryPhased = "phased"
+	// categoryUnclassified is the discovery category of updates that could not be classified
+	categoryUnclassified = "unclassified"
+)
+
+// UpdateDiscoveryEntry is a single low-level discovery row describing a pending package update
+type UpdateDiscoveryEntry struct {
+	Name     string `json:"{#PKG.NAME}"`
+	Current  string `json:"{#PKG.CURRENT}"`
+	Target   string `json:"{#PKG.TARGET}"`
+	Category string `json:"{#PKG.CATEGORY}"`
+	Phased   string `json:"{#PKG.PHASED}"` // "1" for phased updates, "0" otherwise
+}
+
+// DiscoverUpdates returns Zabbix low-level discovery data for pending package updates
+func (h *Handler) DiscoverUpdates(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	updateType, _ := getUpdateTypeAndFlagsFromExtra([]string{metricParams[params.Type]})
+
+	err := h.checkUpdateType(updateType)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	return buildUpdateDiscovery(result, updateType), nil
+}
+
+// buildUpdateDiscovery converts the categorized update lists into discovery rows.
+// Every package gets its most significant category: phased, security, optional, then recommended.
+func buildUpdateDiscovery(result *AllUpdatesResult, updateType UpdateType) []UpdateDiscoveryEntry {
+	categories := make(map[string]string, len(result.AllUpdatesDetails))
+	for _, pkg := range result.RecommendedUpdatesDetails {
+		categories[pkg.Name] = string(UpdateTypeRecommended)
+	}
+	for _, pkg := range result.OptionalUpdatesDetails {
+		categories[pkg.Name] = string(UpdateTypeOptional)
+	}
+	for _, pkg := range result.SecurityUpdatesDetails {
+		categories[pkg.Name] = string(UpdateTypeSecurity)
+	}
+	for _, pkg := range result.PhasedUpdatesDetails {
+		categories[pkg.Name] = categoryPhased
+	}
+
+	var source []UpdateInfo
+	switch updateType {
+	case UpdateTypeSecurity:
+		source = result.SecurityUpdatesDetails
+	case UpdateTypeRecommended:
+		source = result.RecommendedUpdatesDetails
+	case UpdateTypeOptional:
+		source = result.OptionalUpdatesDetails
+	case UpdateTypeAll:
+		source = result.AllUpdatesDetails
+	default:
+		if updates, ok := result.CategoryUpdates[string(updateType)]; ok {
+			source = updates.Details
+		}
+	}
+
+	entries := make([]UpdateDiscoveryEntry, 0, len(source))
+	for _, pkg := range source {
+		phased := "0"
+		if isPhasedUpdate(pkg) {
+			phased = "1"
+		}
+
+		category, ok := categories[pkg.Name]
+		if !ok {
+			// Classification failed for this package (e.g. apt-cache policy error)
+			category = categoryUnclassified
+		}
+
+		entries = append(entries, UpdateDiscoveryEntry{
+			Name:     pkg.Name,
+			Current:  pkg.Current,
+			Target:   pkg.Target,
+			Category: category,
+			Phased:   phased,
+		})
+	}
+
+	return entries
+}
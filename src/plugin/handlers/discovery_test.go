@@ -0,0 +1,65 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBuildUpdateDiscovery ensures discovery rows carry the most significant category of every package
+func TestBuildUpdateDiscovery(t *testing.T) {
+	openssl := UpdateInfo{Name: "openssl", Current: "3.0.13-0ubuntu3.4", Target: "3.0.13-0ubuntu3.5"}
+	vim := UpdateInfo{Name: "vim", Current: "2:9.1.0016-1ubuntu7.2", Target: "2:9.1.0016-1ubuntu7.3"}
+	htop := UpdateInfo{Name: "htop", Current: "3.3.0-4", Target: "3.3.0-4build1"}
+	mesa := UpdateInfo{Name: "mesa-vulkan-drivers", Current: "24.0.9", Target: "24.2.8", IsPhased: true}
+
+	result := &AllUpdatesResult{
+		AllUpdatesDetails:         []UpdateInfo{openssl, vim, htop, mesa},
+		SecurityUpdatesDetails:    []UpdateInfo{openssl},
+		RecommendedUpdatesDetails: []UpdateInfo{openssl, vim},
+		OptionalUpdatesDetails:    []UpdateInfo{},
+		PhasedUpdatesDetails:      []UpdateInfo{mesa},
+	}
+
+	entries := buildUpdateDiscovery(result, UpdateTypeAll)
+	assert.Equal(t, []UpdateDiscoveryEntry{
+		{Name: "openssl", Current: "3.0.13-0ubuntu3.4", Target: "3.0.13-0ubuntu3.5", Category: "security", Phased: "0"},
+		{Name: "vim", Current: "2:9.1.0016-1ubuntu7.2", Target: "2:9.1.0016-1ubuntu7.3", Category: "recommended", Phased: "0"},
+		{Name: "htop", Current: "3.3.0-4", Target: "3.3.0-4build1", Category: "unclassified", Phased: "0"},
+		{Name: "mesa-vulkan-drivers", Current: "24.0.9", Target: "24.2.8", Category: "phased", Phased: "1"},
+	}, entries)
+
+	entries = buildUpdateDiscovery(result, UpdateTypeSecurity)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "openssl", entries[0].Name)
+
+	// An empty category must still serialize as an LLD array, not null
+	entries = buildUpdateDiscovery(result, UpdateTypeOptional)
+	out, err := json.Marshal(entries)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(out))
+
+	out, err = json.Marshal(buildUpdateDiscovery(result, UpdateTypeSecurity))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"{#PKG.NAME}":"openssl","{#PKG.CURRENT}":"3.0.13-0ubuntu3.4",`+
+		`"{#PKG.TARGET}":"3.0.13-0ubuntu3.5","{#PKG.CATEGORY}":"security","{#PKG.PHASED}":"0"}]`, string(out))
+}
@@ -0,0 +1,225 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"sort"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// flagReinstallRequired is the dpkg error flag of packages that failed to unpack
+	flagReinstallRequired = "reinstreq"
+	// sectionDpkgHealth names the dpkg health in AllUpdatesResult.SectionErrors
+	sectionDpkgHealth = "dpkg_health"
+)
+
+// brokenStates are the package states an interrupted or failed dpkg run leaves behind
+//
+//nolint:gochecknoglobals // lookup table.
+var brokenStates = map[string]bool{
+	stateHalfInstalled:   true,
+	stateHalfConfigured:  true,
+	stateUnpacked:        true,
+	stateTriggersAwaited: true,
+	stateTriggersPending: true,
+}
+
+// aptLockErrors are the messages of apt-get check failing to lock the dpkg database, without
+// root or while another apt or dpkg process holds the lock; they tell nothing about the packages
+//
+//nolint:gochecknoglobals // lookup table.
+var aptLockErrors = []string{
+	"Could not open lock file", "Could not get lock", "Unable to acquire the dpkg frontend lock",
+	"Unable to lock", "are you root?",
+}
+
+// DpkgHealth describes whether dpkg can install packages
+type DpkgHealth struct {
+	Healthy             bool            `json:"healthy"` // No broken packages, no pending journal and no apt-get check errors
+	BrokenPackagesCount int             `json:"broken_packages_count"`
+	BrokenPackages      []BrokenPackage `json:"broken_packages"`
+	JournalPending      bool            `json:"journal_pending"` // An interrupted dpkg run left entries in /var/lib/dpkg/updates
+	JournalEntries      int             `json:"journal_entries"`
+	CheckErrorsCount    int             `json:"check_errors_count"`
+	CheckErrors         []string        `json:"check_errors"` // Unmet dependencies and errors reported by apt-get check
+	// CheckSkipped tells why apt-get check could not lock the dpkg database, the dependencies are then not checked
+	CheckSkipped string `json:"check_skipped,omitempty"`
+}
+
+// BrokenPackage is a package left in an intermediate state by dpkg
+type BrokenPackage struct {
+	Name              string `json:"name"`
+	Architecture      string `json:"architecture"`
+	Version           string `json:"version"`
+	Status            string `json:"status"`
+	ReinstallRequired bool   `json:"reinstall_required"`
+}
+
+// GetDpkgHealth returns the packages in intermediate states, the pending dpkg journal
+// and the apt-get check errors
+func (h *Handler) GetDpkgHealth(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.dpkgHealth == nil {
+		return nil, result.sectionError(sectionDpkgHealth, "failed to check dpkg health")
+	}
+
+	return result.dpkgHealth, nil
+}
+
+// dpkgHealth checks the dpkg database, reads the journal and runs apt-get check
+func (h *Handler) dpkgHealth(ctx context.Context, db dpkgDatabase) (*DpkgHealth, error) {
+	journal, err := h.dpkgJournal()
+	if err != nil {
+		return nil, err
+	}
+
+	health := &DpkgHealth{
+		BrokenPackages: brokenPackages(db),
+		JournalPending: len(journal) > 0,
+		JournalEntries: len(journal),
+	}
+	health.BrokenPackagesCount = len(health.BrokenPackages)
+
+	health.CheckErrors, health.CheckSkipped, err = h.aptCheck(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	health.CheckErrorsCount = len(health.CheckErrors)
+	health.Healthy = health.BrokenPackagesCount == 0 && !health.JournalPending && health.CheckErrorsCount == 0
+
+	return health, nil
+}
+
+// brokenPackages returns the packages in one of the brokenStates or flagged for
+// reinstallation, sorted by name
+func brokenPackages(db dpkgDatabase) []BrokenPackage {
+	broken := []BrokenPackage{}
+
+	for _, entries := range db {
+		for _, pkg := range entries {
+			if !brokenStates[pkg.Status] && pkg.Flag != flagReinstallRequired {
+				continue
+			}
+
+			broken = append(broken, BrokenPackage{
+				Name:              pkg.Name,
+				Architecture:      pkg.Architecture,
+				Version:           pkg.Version,
+				Status:            pkg.Status,
+				ReinstallRequired: pkg.Flag == flagReinstallRequired,
+			})
+		}
+	}
+
+	sort.Slice(broken, func(i, j int) bool {
+		if broken[i].Name != broken[j].Name {
+			return broken[i].Name < broken[j].Name
+		}
+
+		return broken[i].Architecture < broken[j].Architecture
+	})
+
+	return broken
+}
+
+// aptCheck runs apt-get check, which verifies the dependencies of the installed packages.
+// If it cannot lock the dpkg database no problems are returned, with the lock errors
+// as the reason the check was skipped.
+func (h *Handler) aptCheck(ctx context.Context) ([]string, string, error) {
+	output, err := h.sysCalls.execCommand(ctx, "env", "LC_ALL=C", "LANG=C", "apt-get", "check")
+
+	problems := parseAptCheck(string(output))
+	if err != nil && len(problems) == 0 {
+		// apt-get did not run or failed without telling why
+		return nil, "", errs.Wrap(err, "failed to execute apt-get check")
+	}
+
+	var lockErrors []string
+	for _, problem := range problems {
+		if isAptLockError(problem) {
+			lockErrors = append(lockErrors, problem)
+		}
+	}
+
+	if len(lockErrors) > 0 {
+		// apt-get stops before checking anything
+		return []string{}, strings.Join(lockErrors, "; "), nil
+	}
+
+	return problems, "", nil
+}
+
+// isAptLockError reports whether an apt-get error is about the dpkg lock rather than the packages
+func isAptLockError(problem string) bool {
+	for _, message := range aptLockErrors {
+		if strings.Contains(problem, message) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseAptCheck returns the unmet dependencies and errors of apt-get check:
+//
+//	The following packages have unmet dependencies:
+//	 libfoo1 : Depends: libbar2 (>= 2.0) but 1.9-1 is installed
+//	           Depends: libbaz0 but it is not installed
+//	E: Unmet dependencies. Try 'apt --fix-broken install' with no packages (or specify a solution).
+func parseAptCheck(output string) []string {
+	problems := []string{}
+	inDependencies := false
+	pkg := ""
+
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "E: "):
+			problems = append(problems, strings.TrimPrefix(line, "E: "))
+			inDependencies = false
+		case strings.HasPrefix(line, "The following packages have unmet dependencies"):
+			inDependencies = true
+		case inDependencies && strings.HasPrefix(line, " ") && trimmed != "":
+			if name, _, ok := strings.Cut(trimmed, " : "); ok {
+				pkg = name
+				problems = append(problems, trimmed)
+			} else if pkg != "" {
+				// Further dependencies of the same package
+				problems = append(problems, pkg+" : "+trimmed)
+			}
+		default:
+			inDependencies = false
+		}
+	}
+
+	return problems
+}
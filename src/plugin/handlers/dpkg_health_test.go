@@ -0,0 +1,155 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBrokenDpkgStatus = testDpkgStatus + `
+Package: linux-image-6.8.0-51-generic
+Status: install ok half-configured
+Architecture: amd64
+Version: 6.8.0-51.52
+
+Package: man-db
+Status: install ok triggers-pending
+Architecture: amd64
+Version: 2.12.0-4build2
+
+Package: vendor-agent
+Status: install reinstreq half-installed
+Architecture: amd64
+Version: 2.1.0
+`
+
+const testAptCheckOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+You might want to run 'apt --fix-broken install' to correct these.
+The following packages have unmet dependencies:
+ vendor-agent : Depends: libvendor1 (>= 2.0) but 1.9-1 is installed
+                Depends: libcurl4t64 but it is not installed
+E: Unmet dependencies. Try 'apt --fix-broken install' with no packages (or specify a solution).
+`
+
+// TestParseAptCheck ensures unmet dependencies and errors are reported one per line
+func TestParseAptCheck(t *testing.T) {
+	assert.Equal(t, []string{
+		"vendor-agent : Depends: libvendor1 (>= 2.0) but 1.9-1 is installed",
+		"vendor-agent : Depends: libcurl4t64 but it is not installed",
+		"Unmet dependencies. Try 'apt --fix-broken install' with no packages (or specify a solution).",
+	}, parseAptCheck(testAptCheckOutput))
+
+	assert.Equal(t, []string{}, parseAptCheck("Reading package lists...\nBuilding dependency tree...\n"))
+}
+
+// TestDpkgHealth ensures broken packages, the pending journal and apt-get check errors are reported
+func TestDpkgHealth(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{
+			"var/lib/dpkg/status":       {Data: []byte(testBrokenDpkgStatus)},
+			"var/lib/dpkg/updates/0000": {Data: []byte("Package: bash\nStatus: install ok unpacked\nArchitecture: amd64\nVersion: 5.2.21-2ubuntu4+b1\n")},
+		},
+		output: testAptCheckOutput,
+		err:    errors.New("exit status 100"),
+	}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	health, err := handler.dpkgHealth(context.Background(), db)
+	require.NoError(t, err)
+
+	assert.False(t, health.Healthy)
+	assert.True(t, health.JournalPending)
+	assert.Equal(t, 1, health.JournalEntries)
+	assert.Equal(t, 3, health.CheckErrorsCount)
+	assert.Equal(t, 4, health.BrokenPackagesCount)
+	assert.Equal(t, []BrokenPackage{
+		{Name: "bash", Architecture: "amd64", Version: "5.2.21-2ubuntu4+b1", Status: stateUnpacked},
+		{Name: "linux-image-6.8.0-51-generic", Architecture: "amd64", Version: "6.8.0-51.52", Status: stateHalfConfigured},
+		{Name: "man-db", Architecture: "amd64", Version: "2.12.0-4build2", Status: stateTriggersPending},
+		{Name: "vendor-agent", Architecture: "amd64", Version: "2.1.0", Status: stateHalfInstalled, ReinstallRequired: true},
+	}, health.BrokenPackages)
+}
+
+// TestDpkgHealthy ensures a clean dpkg database is reported healthy
+func TestDpkgHealthy(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{"var/lib/dpkg/status": {Data: []byte(testDpkgStatus)}},
+		output:    "Reading package lists...\n",
+	}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	health, err := handler.dpkgHealth(context.Background(), db)
+	require.NoError(t, err)
+
+	assert.True(t, health.Healthy)
+	assert.Equal(t, []BrokenPackage{}, health.BrokenPackages)
+	assert.Equal(t, []string{}, health.CheckErrors)
+
+	// apt-get check failing without output is an error, not a healthy system
+	handler.sysCalls = &mockSystemCalls{
+		mockFiles: mockFiles{"var/lib/dpkg/status": {Data: []byte(testDpkgStatus)}},
+		err:       errors.New("exec: \"apt-get\": executable file not found in $PATH"),
+	}
+
+	_, err = handler.dpkgHealth(context.Background(), db)
+	assert.Error(t, err)
+}
+
+// TestDpkgHealthLocked ensures apt-get check failing to take the dpkg lock is not reported as a dependency problem
+func TestDpkgHealthLocked(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{"var/lib/dpkg/status": {Data: []byte(testDpkgStatus)}},
+		output: "E: Could not open lock file /var/lib/dpkg/lock-frontend - open (13: Permission denied)\n" +
+			"E: Unable to acquire the dpkg frontend lock (/var/lib/dpkg/lock-frontend), are you root?\n",
+		err: errors.New("exit status 100"),
+	}}
+
+	res, err := handler.GetDpkgHealth(context.Background(), nil)
+	require.NoError(t, err)
+
+	health := res.(*DpkgHealth)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, []string{}, health.CheckErrors)
+	assert.Zero(t, health.CheckErrorsCount)
+	assert.Contains(t, health.CheckSkipped, "Could not open lock file")
+}
+
+// TestGetDpkgHealthFailed ensures an unreadable dpkg database is reported by the item and in the snapshot
+func TestGetDpkgHealthFailed(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{}}
+
+	_, err := handler.GetDpkgHealth(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to check dpkg health")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.(*AllUpdatesResult).SectionErrors, "dpkg_health")
+}
@@ -0,0 +1,276 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// dpkgStatusFile is the dpkg database of installed packages
+	dpkgStatusFile = "/var/lib/dpkg/status"
+	// dpkgUpdatesDir holds the journal of changes not yet merged into dpkgStatusFile
+	dpkgUpdatesDir = "/var/lib/dpkg/updates"
+)
+
+// Package selection states, the first word of the dpkg Status field
+const (
+	selectionUnknown   = "unknown"
+	selectionInstall   = "install"
+	selectionHold      = "hold"
+	selectionDeinstall = "deinstall"
+	selectionPurge     = "purge"
+)
+
+// Package states, the last word of the dpkg Status field
+const (
+	stateNotInstalled    = "not-installed"
+	stateConfigFiles     = "config-files"
+	stateHalfInstalled   = "half-installed"
+	stateUnpacked        = "unpacked"
+	stateHalfConfigured  = "half-configured"
+	stateTriggersAwaited = "triggers-awaited"
+	stateTriggersPending = "triggers-pending"
+	stateInstalled       = "installed"
+)
+
+// InstalledPackage is the state of a package in the dpkg database
+type InstalledPackage struct {
+	Name          string `json:"name"`
+	Architecture  string `json:"architecture"`
+	Version       string `json:"version"`
+	Source        string `json:"source"`
+	SourceVersion string `json:"source_version"`
+	Want          string `json:"want"`   // Selection state: install, hold, deinstall, purge or unknown
+	Flag          string `json:"flag"`   // Error flag: ok or reinstreq
+	Status        string `json:"status"` // Package state, e.g. installed, unpacked, half-configured
+	Essential     bool   `json:"essential"`
+	Protected     bool   `json:"protected"`
+	Priority      string `json:"priority"` // e.g. required, important, standard, optional
+
+	// conffiles are the configuration files dpkg tracks for the package
+	conffiles []string
+	// depends, recommends and suggests list the alternatives of each relation, see parseRelations
+	depends    [][]string
+	recommends [][]string
+	suggests   [][]string
+	// provides lists the virtual packages the package provides
+	provides []string
+	// installedSize is the Installed-Size in KiB
+	installedSize int64
+}
+
+// IsHeld reports whether the package is on hold
+func (p InstalledPackage) IsHeld() bool {
+	return p.Want == selectionHold
+}
+
+// IsInstalled reports whether the package files are present on the system, in any state
+func (p InstalledPackage) IsInstalled() bool {
+	return p.Status != stateNotInstalled && p.Status != stateConfigFiles && p.Status != ""
+}
+
+// dpkgDatabase maps package names to their entries, one per architecture
+type dpkgDatabase map[string][]InstalledPackage
+
+// installed returns the installed entry of a package. Names may be qualified
+// with an architecture, as apt prints them for foreign architectures.
+func (db dpkgDatabase) installed(name string) (InstalledPackage, bool) {
+	name, arch, qualified := strings.Cut(name, ":")
+
+	for _, pkg := range db[name] {
+		if !pkg.IsInstalled() {
+			continue
+		}
+
+		if !qualified || pkg.Architecture == arch || pkg.Architecture == "all" {
+			return pkg, true
+		}
+	}
+
+	return InstalledPackage{}, false
+}
+
+// readDpkgStatus reads the dpkg database and applies the pending journal
+// entries from dpkgUpdatesDir on top of it, as dpkg does on startup
+func (h *Handler) readDpkgStatus() (dpkgDatabase, error) {
+	data, err := h.readFile(dpkgStatusFile)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read dpkg status")
+	}
+
+	db := make(dpkgDatabase)
+
+	err = parseDeb822(bytes.NewReader(data), func(stanza deb822Stanza) error {
+		db.set(parseInstalledPackage(stanza))
+
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to parse dpkg status")
+	}
+
+	journal, err := h.dpkgJournal()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range journal {
+		data, err := h.readFile(path.Join(dpkgUpdatesDir, name))
+		if err != nil {
+			// The entry may have been merged in the meantime
+			continue
+		}
+
+		err = parseDeb822(bytes.NewReader(data), func(stanza deb822Stanza) error {
+			db.set(parseInstalledPackage(stanza))
+
+			return nil
+		})
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed to parse dpkg journal entry %s", name)
+		}
+	}
+
+	return db, nil
+}
+
+// dpkgJournal returns the names of the pending journal entries in apply order.
+// Entries are named by a sequence number; temporary files are skipped.
+func (h *Handler) dpkgJournal() ([]string, error) {
+	entries, err := h.sysCalls.readDir(dpkgUpdatesDir)
+	if err != nil {
+		// The directory only exists once dpkg has run
+		return nil, nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err == nil && !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		a, _ := strconv.Atoi(names[i])
+		b, _ := strconv.Atoi(names[j])
+
+		return a < b
+	})
+
+	return names, nil
+}
+
+// set stores pkg, replacing an existing entry of the same architecture
+func (db dpkgDatabase) set(pkg InstalledPackage) {
+	if pkg.Name == "" {
+		return
+	}
+
+	entries := db[pkg.Name]
+	for i := range entries {
+		if entries[i].Architecture == pkg.Architecture {
+			entries[i] = pkg
+
+			return
+		}
+	}
+
+	db[pkg.Name] = append(entries, pkg)
+}
+
+// parseInstalledPackage converts a dpkg status stanza into an InstalledPackage
+func parseInstalledPackage(stanza deb822Stanza) InstalledPackage {
+	pkg := InstalledPackage{
+		Name:         stanza["Package"],
+		Architecture: stanza["Architecture"],
+		Version:      stanza["Version"],
+		Essential:    stanza["Essential"] == "yes",
+		Protected:    stanza["Protected"] == "yes",
+		Priority:     stanza["Priority"],
+	}
+
+	// Status: <want> <flag> <status>
+	status := strings.Fields(stanza["Status"])
+	if len(status) == 3 {
+		pkg.Want, pkg.Flag, pkg.Status = status[0], status[1], status[2]
+	}
+
+	// Conffiles: one " <path> <md5sum> [obsolete|remove-on-upgrade]" line per file
+	for _, line := range strings.Split(stanza["Conffiles"], "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			pkg.conffiles = append(pkg.conffiles, fields[0])
+		}
+	}
+
+	pkg.depends = append(parseRelations(stanza["Pre-Depends"]), parseRelations(stanza["Depends"])...)
+	pkg.recommends = parseRelations(stanza["Recommends"])
+	pkg.suggests = parseRelations(stanza["Suggests"])
+
+	for _, provided := range parseRelations(stanza["Provides"]) {
+		pkg.provides = append(pkg.provides, provided...)
+	}
+
+	pkg.installedSize, _ = strconv.ParseInt(stanza["Installed-Size"], 10, 64)
+
+	// Source: <name> [(<version>)], defaulting to the binary package
+	pkg.Source, pkg.SourceVersion = pkg.Name, pkg.Version
+	if source := stanza["Source"]; source != "" {
+		name, version, ok := strings.Cut(source, " ")
+		pkg.Source = name
+
+		if ok {
+			pkg.SourceVersion = strings.Trim(strings.TrimSpace(version), "()")
+		}
+	}
+
+	return pkg
+}
+
+// parseRelations returns the package names of a relation field, one slice of
+// alternatives per relation. Version constraints and architecture qualifiers
+// are dropped: "libc6 (>= 2.34), awk | mawk:any" becomes [[libc6] [awk mawk]].
+func parseRelations(value string) [][]string {
+	var relations [][]string
+
+	for _, relation := range strings.Split(value, ",") {
+		var alternatives []string
+		for _, alternative := range strings.Split(relation, "|") {
+			fields := strings.Fields(strings.NewReplacer("(", " (", "[", " [").Replace(alternative))
+			if len(fields) == 0 {
+				continue
+			}
+
+			name, _, _ := strings.Cut(fields[0], ":")
+			alternatives = append(alternatives, name)
+		}
+
+		if len(alternatives) > 0 {
+			relations = append(relations, alternatives)
+		}
+	}
+
+	return relations
+}
@@ -0,0 +1,105 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDpkgStatus = `Package: libc6
+Status: install ok installed
+Priority: optional
+Section: libs
+Architecture: amd64
+Multi-Arch: same
+Source: glibc
+Version: 2.39-0ubuntu8.3
+Protected: yes
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Multi-Arch: same
+Source: glibc
+Version: 2.39-0ubuntu8.3
+Protected: yes
+
+Package: openssl
+Status: hold ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.4
+
+Package: bash
+Essential: yes
+Status: install ok installed
+Architecture: amd64
+Source: bash (5.2.21-2ubuntu4)
+Version: 5.2.21-2ubuntu4+b1
+
+Package: nginx
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.24.0-2ubuntu7
+Conffiles:
+ /etc/nginx/nginx.conf 5a2a3e6c8d2b5b54dd1c22d5a6e0c0b2
+`
+
+// TestReadDpkgStatus ensures status stanzas and pending journal entries are merged
+func TestReadDpkgStatus(t *testing.T) {
+	handler := &Handler{
+		sysCalls: &mockSystemCalls{mockFiles: mockFiles(fstest.MapFS{
+			"var/lib/dpkg/status": {Data: []byte(testDpkgStatus)},
+			// An interrupted upgrade of openssl left it unpacked
+			"var/lib/dpkg/updates/0002": {Data: []byte("Package: openssl\nStatus: hold ok unpacked\n" +
+				"Architecture: amd64\nVersion: 3.0.13-0ubuntu3.5\n")},
+			"var/lib/dpkg/updates/0001": {Data: []byte("Package: openssl\nStatus: hold ok half-installed\n" +
+				"Architecture: amd64\nVersion: 3.0.13-0ubuntu3.4\n")},
+			"var/lib/dpkg/updates/tmp.i": {Data: []byte("Package: broken\n")},
+		})},
+	}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	assert.Len(t, db["libc6"], 2)
+	libc, ok := db.installed("libc6:i386")
+	require.True(t, ok)
+	assert.Equal(t, "i386", libc.Architecture)
+	assert.Equal(t, "glibc", libc.Source)
+	assert.True(t, libc.Protected)
+
+	openssl, ok := db.installed("openssl")
+	require.True(t, ok)
+	assert.Equal(t, "3.0.13-0ubuntu3.5", openssl.Version)
+	assert.Equal(t, stateUnpacked, openssl.Status)
+	assert.True(t, openssl.IsHeld())
+
+	bash, ok := db.installed("bash")
+	require.True(t, ok)
+	assert.True(t, bash.Essential)
+	assert.Equal(t, "5.2.21-2ubuntu4", bash.SourceVersion)
+
+	_, ok = db.installed("nginx")
+	assert.False(t, ok, "packages with only config files left are not installed")
+	assert.NotContains(t, db, "broken")
+}
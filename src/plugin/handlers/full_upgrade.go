@@ -0,0 +1,176 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"slices"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// modeFullUpgrade is the mode item parameter value selecting the full upgrade simulation
+const modeFullUpgrade = "full-upgrade"
+
+// keptBackHeader starts the list of packages apt-get upgrade does not upgrade
+// because that would need new packages or removals
+const keptBackHeader = "The following packages have been kept back:"
+
+// FullUpgradeResult contains what a full upgrade would do beyond a plain upgrade
+type FullUpgradeResult struct {
+	KeptBackCount    int          `json:"kept_back_count"`
+	NewInstallsCount int          `json:"new_installs_count"`
+	RemovalsCount    int          `json:"removals_count"`
+	KeptBack         []UpdateInfo `json:"kept_back"`    // Target is empty when a full upgrade cannot upgrade the package either
+	NewInstalls      []UpdateInfo `json:"new_installs"` // Packages a full upgrade would newly install
+	Removals         []UpdateInfo `json:"removals"`     // Packages a full upgrade would remove, with their installed version
+}
+
+// simulation is the parsed output of apt-get -s
+type simulation struct {
+	installs    []UpdateInfo // All Inst lines in order, upgrades and new installs
+	upgrades    []UpdateInfo
+	newInstalls []UpdateInfo
+	removals    []UpdateInfo
+	keptBack    []string
+}
+
+// fullUpgrade simulates apt-get dist-upgrade and compares it with the updates of a plain upgrade
+func (h *Handler) fullUpgrade(ctx context.Context, upgrade *CheckResult, db dpkgDatabase) (*FullUpgradeResult, error) {
+	// Force C locale so parsing is stable, phased updates are deferred as in the first upgrade pass
+	output, err := h.sysCalls.execCommand(ctx, "env", "LC_ALL=C", "LANG=C", "apt-get", "-s",
+		"-o", "APT::Get::Always-Include-Phased-Updates=false", "dist-upgrade")
+	if err != nil && len(output) == 0 {
+		return nil, errs.Wrap(err, "failed to execute apt-get -s dist-upgrade")
+	}
+
+	sim := parseSimulation(string(output))
+
+	result := &FullUpgradeResult{
+		KeptBack:    []UpdateInfo{},
+		NewInstalls: sim.newInstalls,
+		Removals:    sim.removals,
+	}
+
+	upgraded := make(map[string]bool, len(upgrade.PackageDetailsList))
+	for _, pkg := range upgrade.PackageDetailsList {
+		upgraded[pkg.Name] = true
+	}
+
+	// Upgrades a plain upgrade does not do
+	for _, pkg := range sim.upgrades {
+		if !upgraded[pkg.Name] {
+			result.KeptBack = append(result.KeptBack, pkg)
+		}
+	}
+
+	// Packages kept back by both, e.g. because of unresolvable dependencies
+	for _, name := range slices.Concat(upgrade.keptBack, sim.keptBack) {
+		if slices.ContainsFunc(result.KeptBack, func(pkg UpdateInfo) bool { return pkg.Name == name }) {
+			continue
+		}
+
+		pkg := UpdateInfo{Name: name}
+		if installed, ok := db.installed(name); ok {
+			pkg.Current = installed.Version
+		}
+
+		result.KeptBack = append(result.KeptBack, pkg)
+	}
+
+	if result.NewInstalls == nil {
+		result.NewInstalls = []UpdateInfo{}
+	}
+
+	if result.Removals == nil {
+		result.Removals = []UpdateInfo{}
+	}
+
+	result.KeptBackCount = len(result.KeptBack)
+	result.NewInstallsCount = len(result.NewInstalls)
+	result.RemovalsCount = len(result.Removals)
+
+	return result, nil
+}
+
+// parseSimulation parses the actions and the kept back section of apt-get -s:
+//
+//	The following packages have been kept back:
+//	  linux-generic linux-image-generic
+//	Inst libssl3t64 [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+//	Inst linux-image-6.8.0-52-generic (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+//	Remv linux-image-6.8.0-41-generic [6.8.0-41.41]
+//	Conf libssl3t64 (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+func parseSimulation(output string) simulation {
+	var sim simulation
+
+	inKeptBack := false
+
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		line := sc.Text()
+
+		if inKeptBack {
+			if strings.HasPrefix(line, " ") {
+				sim.keptBack = append(sim.keptBack, strings.Fields(line)...)
+
+				continue
+			}
+
+			inKeptBack = false
+		}
+
+		switch {
+		case line == keptBackHeader:
+			inKeptBack = true
+		case strings.HasPrefix(line, "Inst "):
+			pkg, ok := parseInstLine(line)
+			if !ok {
+				continue
+			}
+
+			sim.installs = append(sim.installs, pkg)
+
+			if pkg.Current == "" {
+				sim.newInstalls = append(sim.newInstalls, pkg)
+			} else {
+				sim.upgrades = append(sim.upgrades, pkg)
+			}
+		case strings.HasPrefix(line, "Remv "):
+			// Remv <pkg> [<installed version>]
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+
+			pkg := UpdateInfo{Name: fields[1]}
+			if len(fields) > 2 && strings.HasPrefix(fields[2], "[") {
+				pkg.Current = strings.Trim(fields[2], "[]")
+			}
+
+			sim.removals = append(sim.removals, pkg)
+		case strings.HasPrefix(line, "Conf "):
+			// Every configured package was unpacked by an Inst line before
+		}
+	}
+
+	return sim
+}
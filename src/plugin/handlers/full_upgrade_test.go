@@ -0,0 +1,155 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUpgradeOutput = `NOTE: This is only a simulation!
+      apt-get needs root privileges for real execution.
+      Keep also in mind that locking is deactivated,
+      so don't depend on the relevance to the real current situation!
+Reading package lists...
+Building dependency tree...
+Reading state information...
+Calculating upgrade...
+The following packages have been kept back:
+  linux-generic linux-headers-generic
+  linux-image-generic
+The following packages will be upgraded:
+  libssl3t64 openssl
+2 upgraded, 0 newly installed, 0 to remove and 3 not upgraded.
+Inst libssl3t64 [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Inst openssl [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Conf libssl3t64 (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Conf openssl (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+`
+
+const testDistUpgradeOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+Calculating upgrade...
+The following packages were automatically installed and are no longer required:
+  linux-headers-6.8.0-41 linux-headers-6.8.0-41-generic
+Use 'sudo apt autoremove' to remove them.
+The following packages will be REMOVED:
+  legacy-tool
+The following NEW packages will be installed:
+  linux-headers-6.8.0-52 linux-image-6.8.0-52-generic
+The following packages have been kept back:
+  broken-pkg
+The following packages will be upgraded:
+  libssl3t64 linux-generic linux-headers-generic linux-image-generic openssl
+5 upgraded, 2 newly installed, 1 to remove and 1 not upgraded.
+Remv legacy-tool [2.1-3]
+Inst linux-headers-6.8.0-52 (6.8.0-52.53 Ubuntu:24.04/noble-updates [all])
+Inst linux-image-6.8.0-52-generic (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+Inst libssl3t64 [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Inst openssl [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Inst linux-headers-generic [6.8.0-51.52] (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64]) []
+Inst linux-image-generic [6.8.0-51.52] (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64]) []
+Inst linux-generic [6.8.0-51.52] (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+Conf linux-headers-6.8.0-52 (6.8.0-52.53 Ubuntu:24.04/noble-updates [all])
+Conf linux-image-6.8.0-52-generic (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+Conf libssl3t64 (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Conf openssl (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates [amd64])
+Conf linux-headers-generic (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+Conf linux-image-generic (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+Conf linux-generic (6.8.0-52.53 Ubuntu:24.04/noble-updates [amd64])
+`
+
+// TestParseSimulation ensures upgrades, new installs, removals and kept back packages are parsed
+func TestParseSimulation(t *testing.T) {
+	sim := parseSimulation(testUpgradeOutput)
+	assert.Equal(t, []string{"linux-generic", "linux-headers-generic", "linux-image-generic"}, sim.keptBack)
+	assert.Len(t, sim.upgrades, 2)
+	assert.Empty(t, sim.newInstalls)
+	assert.Empty(t, sim.removals)
+
+	sim = parseSimulation(testDistUpgradeOutput)
+	assert.Equal(t, []string{"broken-pkg"}, sim.keptBack)
+	assert.Len(t, sim.upgrades, 5)
+	assert.Len(t, sim.installs, 7)
+	noble := []InstSource{{Label: "Ubuntu", Version: "24.04", Suite: "noble-updates"}}
+	assert.Equal(t, []UpdateInfo{
+		{Name: "linux-headers-6.8.0-52", Target: "6.8.0-52.53", Architecture: "all", Sources: noble},
+		{Name: "linux-image-6.8.0-52-generic", Target: "6.8.0-52.53", Architecture: "amd64", Sources: noble},
+	}, sim.newInstalls)
+	assert.Equal(t, []UpdateInfo{{Name: "legacy-tool", Current: "2.1-3"}}, sim.removals)
+}
+
+// TestFullUpgradeMode ensures kept back packages are reported and selectable with the mode parameter
+func TestFullUpgradeMode(t *testing.T) {
+	handler := &Handler{sysCalls: &simulationSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status": {Data: []byte("Package: broken-pkg\nStatus: install ok installed\nArchitecture: amd64\nVersion: 1.0-1\n")},
+	}}}
+
+	result, err := handler.getAllUpdates(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.AllUpdatesCount)
+	require.NotNil(t, result.FullUpgrade)
+	assert.Equal(t, 4, result.FullUpgrade.KeptBackCount)
+	keptBack := make([]string, 0, len(result.FullUpgrade.KeptBack))
+	for _, pkg := range result.FullUpgrade.KeptBack {
+		keptBack = append(keptBack, pkg.Name+" "+pkg.Current+" "+pkg.Target)
+	}
+	assert.Equal(t, []string{
+		"linux-headers-generic 6.8.0-51.52 6.8.0-52.53",
+		"linux-image-generic 6.8.0-51.52 6.8.0-52.53",
+		"linux-generic 6.8.0-51.52 6.8.0-52.53",
+		"broken-pkg 1.0-1 ",
+	}, keptBack)
+
+	// Kept back packages are classified like the regular updates
+	assert.Equal(t, "updates", result.FullUpgrade.KeptBack[0].Pocket)
+	assert.NotEmpty(t, result.FullUpgrade.KeptBack[0].Repository)
+	assert.Empty(t, result.FullUpgrade.KeptBack[3].Pocket)
+	assert.Equal(t, 2, result.FullUpgrade.NewInstallsCount)
+	assert.Equal(t, 1, result.FullUpgrade.RemovalsCount)
+
+	count, err := handler.CheckUpdateCount(context.Background(), map[string]string{"type": "all", "mode": "upgrade"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = handler.CheckUpdateCount(context.Background(), map[string]string{"type": "all", "mode": "full-upgrade"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+// simulationSystemCalls returns different apt-get -s output for upgrade and dist-upgrade
+type simulationSystemCalls struct {
+	mockFiles
+}
+
+func (s *simulationSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	switch args[len(args)-1] {
+	case "upgrade":
+		return []byte(testUpgradeOutput), nil
+	case "dist-upgrade":
+		return []byte(testDistUpgradeOutput), nil
+	}
+
+	return []byte{}, nil
+}
@@ -23,13 +23,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"zabbix-agent2-apt-updates/src/plugin/params"
 	"golang.zabbix.com/sdk/errs"
+	"golang.zabbix.com/sdk/log"
 )
 
 var (
@@ -37,6 +44,7 @@ var (
 	_ HandlerFunc = (*Handler)(nil).CheckUpdateCount
 	_ HandlerFunc = (*Handler)(nil).GetUpdateList
 	_ HandlerFunc = (*Handler)(nil).GetUpdateDetails
+	_ HandlerFunc = (*Handler)(nil).DiscoverUpdates
 	_ systemCalls = osWrapper{}
 )
 
@@ -60,6 +68,11 @@ const (
 // Handler holds syscall implementation for request functions.
 type Handler struct {
 	sysCalls systemCalls
+	cache    atomic.Pointer[updateCache]
+	rules    atomic.Pointer[[]classificationRule]
+	installs installTracker
+	// logger is set while the background collector runs, see warnf
+	logger atomic.Pointer[log.Logger]
 }
 
 // GetAllUpdates returns comprehensive information about all available APT updates
@@ -83,16 +96,122 @@ type AllUpdatesResult struct {
 	OptionalUpdatesDetails   []UpdateInfo `json:"optional_updates_details,omitempty"`
 	AllUpdatesDetails      []UpdateInfo `json:"all_updates_details,omitempty"`
 
+	// Updates of the categories defined by the configured rules, phased updates excluded
+	CategoryUpdates map[string]*CategoryUpdates `json:"category_updates,omitempty"`
+
+	// Automatically installed packages nothing depends on anymore, as apt autoremove would remove them;
+	// all null if they could not be determined, see SectionErrors
+	AutoremovableCount   *int                   `json:"autoremovable_count"`
+	AutoremovableList    []string               `json:"autoremovable_list"`
+	AutoremovableDetails []AutoremovablePackage `json:"autoremovable_details"`
+
+	// Installed versions no configured repository provides, see obsoletePackages;
+	// all null if they could not be determined, see SectionErrors
+	ObsoletePackagesCount   *int              `json:"obsolete_packages_count"`
+	ObsoletePackagesList    []string          `json:"obsolete_packages_list"`
+	ObsoletePackagesDetails []ObsoletePackage `json:"obsolete_packages_details"`
+
+	// Pending updates grouped by the repository they come from, most updates first
+	RepositoryUpdates []RepositoryUpdates `json:"repository_updates"`
+
+	// Packages on hold, which never appear in the Inst lines, with the updates they keep back
+	HeldPackagesCount         int           `json:"held_packages_count"`
+	HeldSecurityUpdatesCount  int           `json:"held_security_updates_count"`
+	HeldPackagesList          []string      `json:"held_packages_list"`
+	HeldPackagesDetails       []HeldPackage `json:"held_packages_details"`
+
+	// Configuration file versions dpkg and ucf left in /etc, nil if the scan failed
+	ConffileLeftovers *ConffileStatus `json:"conffile_leftovers,omitempty"`
+
+	// Downgrades and security updates kept back by pin priorities, nil if the check failed
+	PinAnomalies *PinAnomalies `json:"pin_anomalies,omitempty"`
+
+	// What a full upgrade would do beyond a plain upgrade, nil if the simulation failed
+	FullUpgrade *FullUpgradeResult `json:"full_upgrade,omitempty"`
+
+	// Pending reboot, nil if the flag files could not be read, see SectionErrors
+	RebootRequired *RebootStatus `json:"reboot_required,omitempty"`
+	// Running kernel compared with the installed ones, nil if it could not be read, see SectionErrors
+	Kernel *KernelStatus `json:"kernel,omitempty"`
+
 	CheckDurationSeconds float64 `json:"check_duration_seconds"`
 	LastAptUpdateTime     int64    `json:"last_apt_update_time"` // Unix timestamp in seconds
+	SnapshotAgeSeconds   float64 `json:"snapshot_age_seconds"`
+	LastError            string  `json:"last_error"` // Error of the last refresh, empty if it succeeded
+
+	// Sections left out of the snapshot because they could not be collected, with the errors
+	SectionErrors map[string]string `json:"section_errors,omitempty"`
+
+	// Package changes from the apt and dpkg logs, as Unix timestamps in seconds, 0 if none is logged
+	LastUpgradeTime          int64   `json:"last_upgrade_time"`
+	LastInstallTime          int64   `json:"last_install_time"`
+	LastRemovalTime          int64   `json:"last_removal_time"`
+	MeanTimeToInstallSeconds float64 `json:"mean_time_to_install_seconds"` // See HistoryStatus
+
+	// categories maps package names to the update types they belong to
+	categories map[string]map[UpdateType]bool
+	// history is the package change history behind the Last*Time fields, nil if the logs could not be read
+	history *HistoryStatus
+	// restart is the process scan served by GetRestartRequired, nil if it failed
+	restart *RestartStatus
+	// unattended is the unattended-upgrades status served by GetUnattendedUpgrades, nil if it failed
+	unattended *UnattendedStatus
+	// dpkgHealth is the dpkg health served by GetDpkgHealth, nil if it could not be checked
+	dpkgHealth *DpkgHealth
+	// sectionErrs holds the errors behind SectionErrors for the items serving a single section
+	sectionErrs map[string]error
+}
+
+// setSectionError records that a section of the snapshot could not be collected
+func (r *AllUpdatesResult) setSectionError(section string, err error) {
+	if r.sectionErrs == nil {
+		r.SectionErrors = make(map[string]string)
+		r.sectionErrs = make(map[string]error)
+	}
+
+	r.SectionErrors[section] = err.Error()
+	r.sectionErrs[section] = err
+}
+
+// sectionError returns why a section is missing from the snapshot, wrapped with msg
+func (r *AllUpdatesResult) sectionError(section, msg string) error {
+	err, ok := r.sectionErrs[section]
+	if !ok {
+		err = errs.Errorf("%s not collected", section)
+	}
+
+	return errs.Wrap(err, msg)
 }
 
 // UpdateInfo represents a single package update
 type UpdateInfo struct {
-	Name     string `json:"name"`
-	Current  string `json:"current_version,omitempty"`
-	Target   string `json:"target_version,omitempty"`
-	IsPhased bool   `json:"is_phased,omitempty"` // Indicates if this update is subject to phased rollout
+	Name         string       `json:"name"`
+	Current      string       `json:"current_version,omitempty"`
+	Target       string       `json:"target_version,omitempty"`
+	Architecture string       `json:"architecture,omitempty"`
+	Sources      []InstSource `json:"sources,omitempty"`       // Repositories the target version is available from, as apt-get prints them
+	IsPhased     bool         `json:"is_phased,omitempty"`     // Indicates if this update is subject to phased rollout
+	Pocket       string       `json:"pocket,omitempty"`        // Most significant pocket of the target version: security, updates, release, backports or proposed
+	Repository   string       `json:"repository,omitempty"`    // Repository of that pocket, see releaseFields.repository
+	SecurityRule string       `json:"security_rule,omitempty"` // Rule that classified the update as security, see securityRules
+	Categories   []string     `json:"categories,omitempty"`    // Categories assigned by the configured rules
+
+	// releases are the releases the target version is available from, as used for the classification
+	releases []releaseFields
+}
+
+// InstSource is a repository of the target version, as printed in the Inst lines of apt-get -s
+type InstSource struct {
+	Label   string `json:"label,omitempty"`
+	Version string `json:"version,omitempty"` // Release version, e.g. 24.04
+	Suite   string `json:"suite"`
+}
+
+// CategoryUpdates contains the updates of a category defined by the configured rules
+type CategoryUpdates struct {
+	Count   int          `json:"count"`
+	List    []string     `json:"list"`
+	Details []UpdateInfo `json:"details"`
 }
 
 // CheckResult contains the complete check result
@@ -101,6 +220,11 @@ type CheckResult struct {
 	PackageDetailsList   []UpdateInfo `json:"package_details_list,omitempty"`
 	CheckDurationSeconds float64 `json:"check_duration_seconds"`
 	LastAptUpdateTime     int64       `json:"last_apt_update_time"` // Unix timestamp in seconds
+	SnapshotAgeSeconds   float64 `json:"snapshot_age_seconds"`
+	LastError            string  `json:"last_error"` // Error of the last refresh, empty if it succeeded
+
+	// keptBack lists the packages apt-get upgrade kept back
+	keptBack []string
 }
 
 type commandExecutor interface {
@@ -109,15 +233,16 @@ type commandExecutor interface {
 
 type systemCalls interface {
 	execCommand(ctx context.Context, name string, args ...string) ([]byte, error)
+	openFile(name string) (io.ReadCloser, error)
+	readDir(name string) ([]fs.DirEntry, error)
+	stat(name string) (fs.FileInfo, error)
 }
 
 type osWrapper struct{}
 
 // CheckUpdateCount returns the number of available APT updates
 func (h *Handler) CheckUpdateCount(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
-	updateType := getUpdateTypeFromExtra(extraParams)
-
-	result, err := h.checkAPTUpdates(ctx, updateType, false)
+	result, err := h.updatesOfType(ctx, metricParams)
 	if err != nil {
 		return nil, errs.Wrap(err, "failed to check APT updates")
 	}
@@ -127,14 +252,13 @@ func (h *Handler) CheckUpdateCount(ctx context.Context, metricParams map[string]
 
 // GetUpdateList returns a JSON list of available APT updates
 func (h *Handler) GetUpdateList(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
-	updateType := getUpdateTypeFromExtra(extraParams)
-	result, err := h.checkAPTUpdates(ctx, updateType, false)
+	result, err := h.updatesOfType(ctx, metricParams)
 	if err != nil {
 		return nil, errs.Wrap(err, "failed to check APT updates")
 	}
 
-	// Return just the list of packages
-	var packageNames []string
+	// Return just the list of packages (an empty list rather than null when there are none)
+	packageNames := make([]string, 0, len(result.PackageDetailsList))
 	for _, pkg := range result.PackageDetailsList {
 		packageNames = append(packageNames, pkg.Name)
 	}
@@ -144,8 +268,7 @@ func (h *Handler) GetUpdateList(ctx context.Context, metricParams map[string]str
 
 // GetUpdateDetails returns detailed information about available APT updates
 func (h *Handler) GetUpdateDetails(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
-	updateType := getUpdateTypeFromExtra(extraParams)
-	result, err := h.checkAPTUpdates(ctx, updateType, false)
+	result, err := h.updatesOfType(ctx, metricParams)
 	if err != nil {
 		return nil, errs.Wrap(err, "failed to check APT updates")
 	}
@@ -153,30 +276,74 @@ func (h *Handler) GetUpdateDetails(ctx context.Context, metricParams map[string]
 	return result, nil
 }
 
-// GetAllUpdates returns comprehensive information about all types of available APT updates
-func (h *Handler) GetAllUpdates(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
-	result := &AllUpdatesResult{}
+// updatesOfType selects the updates requested by the item parameters from the current snapshot
+func (h *Handler) updatesOfType(ctx context.Context, metricParams map[string]string) (*CheckResult, error) {
+	updateType, includePhased := getUpdateTypeAndFlagsFromExtra(paramValues(metricParams))
 
-	// Track start time for duration calculation
-	startTime := time.Now()
+	err := h.checkUpdateType(updateType)
+	if err != nil {
+		return nil, err
+	}
 
-	// First pass: get all updates with phased updates excluded (includePhased=false)
-	// This will parse the "deferred due to phasing" section from apt-get output
-	// Pass a slice with nil so checkAPTUpdates can populate it with detected phased packages
-	deferredPackages := []map[string]bool{nil}
-	_, err := h.checkAPTUpdates(ctx, UpdateTypeAll, false, deferredPackages...)
+	snapshot, err := h.snapshot(ctx)
 	if err != nil {
-		return nil, errs.Wrap(err, "failed to check APT updates for first pass")
+		return nil, err
 	}
 
+	source := snapshot.AllUpdatesDetails
+	if metricParams[params.Mode] == modeFullUpgrade {
+		if snapshot.FullUpgrade == nil {
+			return nil, errs.New("full-upgrade simulation failed")
+		}
+
+		// Kept back packages a full upgrade can upgrade
+		source = slices.Clone(source)
+		for _, pkg := range snapshot.FullUpgrade.KeptBack {
+			if pkg.Target != "" {
+				source = append(source, pkg)
+			}
+		}
+	}
+
+	var updates []UpdateInfo
+	for _, pkg := range source {
+		if isPhasedUpdate(pkg) && !includePhased {
+			continue
+		}
+		if updateType != UpdateTypeAll && !snapshot.categories[pkg.Name][updateType] {
+			continue
+		}
+		updates = append(updates, pkg)
+	}
+
+	return &CheckResult{
+		AvailableUpdates:     len(updates),
+		PackageDetailsList:   updates,
+		CheckDurationSeconds: snapshot.CheckDurationSeconds,
+		LastAptUpdateTime:    snapshot.LastAptUpdateTime,
+		SnapshotAgeSeconds:   snapshot.SnapshotAgeSeconds,
+		LastError:            snapshot.LastError,
+	}, nil
+}
+
+// GetAllUpdates returns comprehensive information about all types of available APT updates
+func (h *Handler) GetAllUpdates(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	return h.snapshot(ctx)
+}
 
-	// Second pass: get all updates including phased ones
-	// Pass the deferred packages map from the first pass so IsPhased can be set correctly
-	var deferredPackagesMap map[string]bool = nil
-	if len(deferredPackages) > 0 && deferredPackages[0] != nil {
-		deferredPackagesMap = deferredPackages[0]
+// getAllUpdates collects all available updates and splits them into phased,
+// security, recommended and optional categories
+func (h *Handler) getAllUpdates(ctx context.Context) (*AllUpdatesResult, error) {
+	result := &AllUpdatesResult{
+		categories: make(map[string]map[UpdateType]bool),
 	}
-	allUpdates, err := h.checkAPTUpdates(ctx, UpdateTypeAll, true, []map[string]bool{deferredPackagesMap}...)
+
+	// Track start time for duration calculation
+	startTime := time.Now()
+
+	// Get all updates including phased ones; collectUpdates runs a first pass
+	// without phased updates so IsPhased can be set correctly
+	allUpdates, err := h.collectUpdates(ctx, UpdateTypeAll, true)
 	if err != nil {
 		return nil, errs.Wrap(err, "failed to check APT updates for 'all'")
 	}
@@ -194,6 +361,15 @@ func (h *Handler) GetAllUpdates(ctx context.Context, metricParams map[string]str
 	result.OptionalUpdatesList = []string{}
 	result.OptionalUpdatesDetails = []UpdateInfo{}
 
+	// Categories of the configured rules are reported even when empty
+	ruleCategories := h.ruleCategories()
+	if len(ruleCategories) > 0 {
+		result.CategoryUpdates = make(map[string]*CategoryUpdates, len(ruleCategories))
+		for _, category := range ruleCategories {
+			result.CategoryUpdates[string(category)] = &CategoryUpdates{List: []string{}, Details: []UpdateInfo{}}
+		}
+	}
+
 	// Get last apt update time from package lists (also available in allUpdates)
 	if allUpdates.LastAptUpdateTime != 0 {
 		result.LastAptUpdateTime = allUpdates.LastAptUpdateTime
@@ -208,6 +384,20 @@ func (h *Handler) GetAllUpdates(ctx context.Context, metricParams map[string]str
 		}
 	}
 
+	// Take current versions missing from the Inst lines from the dpkg database
+	db, dbErr := h.readDpkgStatus()
+	if dbErr == nil {
+		for i, pkg := range allUpdates.PackageDetailsList {
+			if installed, ok := db.installed(pkg.Name); ok && pkg.Current == "" {
+				allUpdates.PackageDetailsList[i].Current = installed.Version
+			}
+		}
+	}
+
+	// Classify all packages at once, from the repository indexes or a single apt-cache call.
+	// This also records the matching security rule in the details.
+	classified := h.classifyUpdates(ctx, allUpdates.PackageDetailsList)
+
 	// Set all updates data (including phased)
 	result.AllUpdatesCount = len(allUpdates.PackageDetailsList)
 	result.AllUpdatesList = make([]string, len(allUpdates.PackageDetailsList))
@@ -220,6 +410,10 @@ func (h *Handler) GetAllUpdates(ctx context.Context, metricParams map[string]str
 	// Filter updates by type in-memory instead of calling apt multiple times
 	// This significantly reduces execution time and prevents timeout issues on ARM platforms
 	for _, pkg := range allUpdates.PackageDetailsList {
+		// Phased updates are classified as well so per-type items can include them on request
+		categories := classified[pkg.Name]
+		result.categories[pkg.Name] = categories
+
 		// Phased updates should be counted separately, not included in regular categories
 		if isPhasedUpdate(pkg) {
 			result.PhasedUpdatesCount++
@@ -228,33 +422,172 @@ func (h *Handler) GetAllUpdates(ctx context.Context, metricParams map[string]str
 			continue
 		}
 
-		isSecurity, err := h.isPackageOfType(ctx, pkg.Name, UpdateTypeSecurity)
-		if err != nil {
-			// If we can't determine the type, skip it for security updates
-			continue
-		}
-		if isSecurity {
+		if categories[UpdateTypeSecurity] {
 			result.SecurityUpdatesCount++
 			result.SecurityUpdatesList = append(result.SecurityUpdatesList, pkg.Name)
 			result.SecurityUpdatesDetails = append(result.SecurityUpdatesDetails, pkg)
 		}
 
-		// For recommended and optional, we use the same logic as before
-		// Recommended is treated as all updates (can be enhanced later)
-		result.RecommendedUpdatesCount++
-		result.RecommendedUpdatesList = append(result.RecommendedUpdatesList, pkg.Name)
-		result.RecommendedUpdatesDetails = append(result.RecommendedUpdatesDetails, pkg)
-
-		isOptional, err := h.isPackageOfType(ctx, pkg.Name, UpdateTypeOptional)
-		if err != nil {
-			// If we can't determine the type, skip it for optional updates
-			continue
+		if categories[UpdateTypeRecommended] {
+			result.RecommendedUpdatesCount++
+			result.RecommendedUpdatesList = append(result.RecommendedUpdatesList, pkg.Name)
+			result.RecommendedUpdatesDetails = append(result.RecommendedUpdatesDetails, pkg)
 		}
-		if isOptional {
+
+		if categories[UpdateTypeOptional] {
 			result.OptionalUpdatesCount++
 			result.OptionalUpdatesList = append(result.OptionalUpdatesList, pkg.Name)
 			result.OptionalUpdatesDetails = append(result.OptionalUpdatesDetails, pkg)
 		}
+
+		for _, category := range ruleCategories {
+			if categories[category] {
+				updates := result.CategoryUpdates[string(category)]
+				updates.Count++
+				updates.List = append(updates.List, pkg.Name)
+				updates.Details = append(updates.Details, pkg)
+			}
+		}
+	}
+
+	result.RepositoryUpdates = repositoryUpdates(result.AllUpdatesDetails, result.categories)
+
+	// What was actually upgraded, as opposed to LastAptUpdateTime which only tells when the lists were refreshed
+	history, err := h.historyStatus(time.Now(), result.AllUpdatesDetails)
+	if err != nil {
+		result.setSectionError(sectionHistory, err)
+	} else {
+		result.history = history
+		result.LastUpgradeTime = history.LastUpgrade.time()
+		result.LastInstallTime = history.LastInstall.time()
+		result.LastRemovalTime = history.LastRemoval.time()
+		result.MeanTimeToInstallSeconds = history.MeanTimeToInstallSeconds
+	}
+
+	// Updates only a full upgrade would install, with their categories for the per-type items
+	full, err := h.fullUpgrade(ctx, allUpdates, db)
+	if err == nil {
+		result.FullUpgrade = full
+
+		var (
+			keptBack []UpdateInfo
+			indexes  []int
+		)
+		for i, pkg := range full.KeptBack {
+			if pkg.Target != "" {
+				keptBack = append(keptBack, pkg)
+				indexes = append(indexes, i)
+			}
+		}
+
+		for name, categories := range h.classifyUpdates(ctx, keptBack) {
+			result.categories[name] = categories
+		}
+
+		// Write back the pocket, repository and security rule classifyUpdates recorded
+		for j, i := range indexes {
+			full.KeptBack[i] = keptBack[j]
+		}
+	}
+
+	// Held packages are taken from the dpkg selections
+	result.HeldPackagesList = []string{}
+	result.HeldPackagesDetails = []HeldPackage{}
+	if db != nil {
+		result.HeldPackagesDetails = h.heldPackages(ctx, db)
+	}
+
+	for _, pkg := range result.HeldPackagesDetails {
+		result.HeldPackagesList = append(result.HeldPackagesList, pkg.Name)
+
+		if pkg.Security {
+			result.HeldSecurityUpdatesCount++
+		}
+	}
+
+	result.HeldPackagesCount = len(result.HeldPackagesDetails)
+
+	if db == nil {
+		result.setSectionError(sectionAutoremovable, dbErr)
+	} else if removable, err := h.autoremovablePackages(ctx, db); err != nil {
+		result.setSectionError(sectionAutoremovable, err)
+	} else {
+		result.AutoremovableList = []string{}
+		result.AutoremovableDetails = removable
+		for _, pkg := range removable {
+			result.AutoremovableList = append(result.AutoremovableList, pkg.Name)
+		}
+
+		count := len(removable)
+		result.AutoremovableCount = &count
+	}
+
+	if db == nil {
+		result.setSectionError(sectionObsolete, dbErr)
+	} else if obsolete, err := h.obsoletePackages(ctx, db); err != nil {
+		result.setSectionError(sectionObsolete, err)
+	} else {
+		result.ObsoletePackagesList = []string{}
+		result.ObsoletePackagesDetails = obsolete
+		for _, pkg := range obsolete {
+			result.ObsoletePackagesList = append(result.ObsoletePackagesList, pkg.Name)
+		}
+
+		count := len(obsolete)
+		result.ObsoletePackagesCount = &count
+	}
+
+	if db != nil {
+		anomalies, err := h.pinAnomalies(ctx, db, allUpdates.PackageDetailsList)
+		if err == nil {
+			result.PinAnomalies = anomalies
+		}
+	}
+
+	if db == nil {
+		result.setSectionError(sectionConffiles, dbErr)
+	} else if leftovers, err := h.conffileLeftovers(ctx, db); err != nil {
+		result.setSectionError(sectionConffiles, err)
+	} else {
+		result.ConffileLeftovers = leftovers
+	}
+
+	reboot, err := h.rebootStatus()
+	if err != nil {
+		result.setSectionError(sectionReboot, err)
+	} else {
+		result.RebootRequired = reboot
+	}
+
+	if db == nil {
+		result.setSectionError(sectionKernel, dbErr)
+	} else if kernel, err := h.kernelStatus(db, result.AllUpdatesDetails); err != nil {
+		result.setSectionError(sectionKernel, err)
+	} else {
+		result.Kernel = kernel
+	}
+
+	if db == nil {
+		result.setSectionError(sectionDpkgHealth, dbErr)
+	} else if health, err := h.dpkgHealth(ctx, db); err != nil {
+		result.setSectionError(sectionDpkgHealth, err)
+	} else {
+		result.dpkgHealth = health
+	}
+
+	unattended, err := h.unattendedStatus(ctx, result.SecurityUpdatesDetails)
+	if err != nil {
+		result.setSectionError(sectionUnattended, err)
+	} else {
+		result.unattended = unattended
+	}
+
+	// Walking /proc and the dpkg file lists is too slow to do on every poll
+	restart, err := h.restartStatus(ctx)
+	if err != nil {
+		result.setSectionError(sectionRestart, err)
+	} else {
+		result.restart = restart
 	}
 
 	return result, nil
@@ -287,21 +620,10 @@ func WithJSONResponse(handler HandlerFunc) HandlerFunc {
 	}
 }
 
-// getUpdateTypeFromExtra extracts the update type from extra parameters
-// When user calls apt.updates[security], Zabbix passes "security" as first extra param
-func getUpdateTypeFromExtra(extraParams []string) UpdateType {
-	if len(extraParams) > 0 {
-		typeStr := strings.TrimSpace(extraParams[0])
-		switch typeStr {
-		case "security":
-			return UpdateTypeSecurity
-		case "recommended":
-			return UpdateTypeRecommended
-		case "optional":
-			return UpdateTypeOptional
-		}
-	}
-	return UpdateTypeAll
+// paramValues returns the metric parameters in the positional order
+// expected by getUpdateTypeAndFlagsFromExtra
+func paramValues(metricParams map[string]string) []string {
+	return []string{metricParams[params.Type], metricParams[params.Phased]}
 }
 
 // getUpdateType extracts the update type from metric parameters (fallback method)
@@ -341,19 +663,23 @@ func getUpdateTypeAndFlagsFromExtra(extraParams []string) (UpdateType, bool) {
 	if len(extraParams) > 0 {
 		typeStr := strings.TrimSpace(extraParams[0])
 		switch typeStr {
+		case "", "all":
 		case "security":
 			updateType = UpdateTypeSecurity
 		case "recommended":
 			updateType = UpdateTypeRecommended
 		case "optional":
 			updateType = UpdateTypeOptional
+		default:
+			// Category defined by the configured rules
+			updateType = UpdateType(typeStr)
 		}
 	}
 
 	// Check for flags - look for strings like "include-phased" or "phased"
 	for _, param := range extraParams {
-		if strings.Contains(strings.ToLower(param), "phased") ||
-		   strings.Contains(strings.ToLower(param), "include") {
+		switch strings.ToLower(strings.TrimSpace(param)) {
+		case "include-phased", "phased", "include":
 			includePhased = true
 		}
 	}
@@ -361,46 +687,22 @@ func getUpdateTypeAndFlagsFromExtra(extraParams []string) (UpdateType, bool) {
 	return updateType, includePhased
 }
 
-// isPackageOfType checks if a package belongs to a specific update type category
-func (h *Handler) isPackageOfType(ctx context.Context, pkgName string, updateType UpdateType) (bool, error) {
-	switch updateType {
-	case UpdateTypeSecurity:
-		// Check if package comes from security repository
-		output, err := h.sysCalls.execCommand(ctx, "apt-cache", "policy", pkgName)
-		if err != nil {
-			return false, fmt.Errorf("failed to check policy for %s: %w", pkgName, err)
-		}
-
-		// Look for security repository in the output
-		outputStr := string(output)
-		// Security packages come from repositories like:
-		//   https://security.ubuntu.com/ubuntu
-		//   http://security.debian.org
-		return strings.Contains(outputStr, "security.") ||
-			strings.Contains(outputStr, "Ubuntu: noble-security") ||
-			strings.Contains(outputStr, "Debian-Security"), nil
-
-	case UpdateTypeRecommended:
-		// For now, treat recommended as all updates (can be enhanced later)
-		// In Debian/Ubuntu, there's no direct way to distinguish recommended vs optional
-		// Both are typically in the main repositories
-		return true, nil
-
-	case UpdateTypeOptional:
-		// Optional packages - these would be from universe/multiverse
-		output, err := h.sysCalls.execCommand(ctx, "apt-cache", "policy", pkgName)
-		if err != nil {
-			return false, fmt.Errorf("failed to check policy for %s: %w", pkgName, err)
-		}
-
-		outputStr := string(output)
-		// Optional packages typically come from universe/multiverse
-		return strings.Contains(outputStr, "universe") ||
-			strings.Contains(outputStr, "multiverse"), nil
+// collectUpdates checks for updates of the given type. When phased updates are
+// included, a first pass without them detects the packages deferred due to
+// phasing so that IsPhased is set correctly in the second pass.
+func (h *Handler) collectUpdates(ctx context.Context, updateType UpdateType, includePhased bool) (*CheckResult, error) {
+	if !includePhased {
+		return h.checkAPTUpdates(ctx, updateType, false)
+	}
 
-	default:
-		return true, nil
+	// Pass a slice with nil so checkAPTUpdates can populate it with detected phased packages
+	deferredPackages := []map[string]bool{nil}
+	_, err := h.checkAPTUpdates(ctx, UpdateTypeAll, false, deferredPackages...)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates for first pass")
 	}
+
+	return h.checkAPTUpdates(ctx, updateType, true, deferredPackages...)
 }
 
 // getLastAptUpdateTime returns the most recent modification time of APT package lists
@@ -496,6 +798,80 @@ func parsePackageLine(line string) (string, string, bool) {
 	return pkgName, version, isPhased
 }
 
+// instLineRe matches the Inst lines of apt-get -s
+// Format: Inst <pkg> [<old>] (<new> <label>:<version>/<suite>[, ...] [<arch>])
+//
+//nolint:gochecknoglobals // compiled once.
+var instLineRe = regexp.MustCompile(`^Inst\s+(\S+)(?:\s+\[([^\]]+)\])?\s+\(([^ )]+)(?:\s+([^)]*))?`)
+
+// parseInstLine parses an Inst line of apt-get -s into an update. Packages of
+// foreign architectures are printed as <pkg>:<arch>.
+func parseInstLine(line string) (UpdateInfo, bool) {
+	m := instLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if len(m) < 5 {
+		return UpdateInfo{}, false
+	}
+
+	pkg := UpdateInfo{
+		Name:    m[1],
+		Current: strings.TrimSpace(m[2]),
+		Target:  strings.TrimSpace(m[3]),
+	}
+
+	pkg.Sources, pkg.Architecture = parseInstSources(m[4])
+	if pkg.Architecture == "" {
+		_, pkg.Architecture, _ = strings.Cut(pkg.Name, ":")
+	}
+
+	return pkg, true
+}
+
+// parseInstSources parses the release part of an Inst line, one entry per
+// repository the target version is available from:
+//
+//	Ubuntu:24.04/noble-updates, Ubuntu:24.04/noble-security [amd64]
+//	Debian-Security:12/stable-security [amd64]
+//	Docker CE:noble [arm64]
+func parseInstSources(rel string) ([]InstSource, string) {
+	rel = strings.TrimSpace(rel)
+
+	arch := ""
+	if i := strings.LastIndex(rel, " ["); i >= 0 && strings.HasSuffix(rel, "]") {
+		arch = rel[i+2 : len(rel)-1]
+		rel = rel[:i]
+	}
+
+	var sources []InstSource
+	for _, entry := range strings.Split(rel, ", ") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var source InstSource
+
+		// The label is omitted for repositories without one
+		label, rest, ok := strings.Cut(entry, ":")
+		if ok {
+			source.Label = label
+		} else {
+			rest = entry
+		}
+
+		version, suite, ok := strings.Cut(rest, "/")
+		if ok {
+			source.Version = version
+			source.Suite = suite
+		} else {
+			source.Suite = rest
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources, arch
+}
+
 // checkAPTUpdates executes 'apt-get -s upgrade' and parses the output
 // This method uses apt-get simulation which respects phased updates by default
 // Using 'apt-get -s upgrade' instead of 'apt list --upgradable' ensures phasing is respected
@@ -543,6 +919,11 @@ func (h *Handler) checkAPTUpdates(ctx context.Context, updateType UpdateType, in
 				continue
 			}
 
+			// The next section, e.g. "The following packages will be upgraded:", ends the list
+			if foundPhasingHeader && strings.HasSuffix(line, ":") {
+				break
+			}
+
 			// If we found the header, collect package names from subsequent lines
 			if foundPhasingHeader && line != "" {
 				// Skip summary lines like "0 upgraded", "1 newly installed", etc.
@@ -577,66 +958,27 @@ func (h *Handler) checkAPTUpdates(ctx context.Context, updateType UpdateType, in
 	}
 
 	// Parse the output from apt-get -s upgrade
-	// Format: Inst <pkg> [<old>] (<new> <repo> ...)
-	re := regexp.MustCompile(`^Inst\s+(\S+)(?:\s+\[([^\]]+)\])?\s+\(([^ )]+)`)
-	sc = bufio.NewScanner(strings.NewReader(string(output)))
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" || !strings.HasPrefix(line, "Inst ") {
-			continue
-		}
-
-		m := re.FindStringSubmatch(line)
-		if len(m) < 4 {
-			continue
-		}
-
-		pkgName := m[1]
-		current := strings.TrimSpace(m[2])
-		target := strings.TrimSpace(m[3])
-
+	sim := parseSimulation(string(output))
+	for _, pkg := range sim.installs {
 		// Check if this package is phased
-		isPhased := false
 		if len(deferredPackages) > 0 && deferredPackages[0] != nil {
 			// If a deferred packages map was provided, check if this package is in it
-			if _, isDeferred := deferredPackages[0][pkgName]; isDeferred {
-				isPhased = true
-			}
-		}
-
-		// Filter by update type if needed
-		if updateType != UpdateTypeAll && updateType != "" {
-			isMatch, err := h.isPackageOfType(ctx, pkgName, updateType)
-			if err != nil {
-				// If we can't determine the type, skip this package for filtered queries
-				continue
-			}
-			if !isMatch {
-				continue
+			if _, isDeferred := deferredPackages[0][pkg.Name]; isDeferred {
+				pkg.IsPhased = true
 			}
 		}
 
-		updates = append(updates, UpdateInfo{
-			Name:    pkgName,
-			Current: current,
-			Target:  target,
-			IsPhased: isPhased,
-		})
+		updates = append(updates, pkg)
 	}
 
 	// Filter updates by type if needed (for security, recommended, optional)
 	if updateType != UpdateTypeAll && updateType != "" {
+		classified := h.classifyUpdates(ctx, updates)
 		filteredUpdates := []UpdateInfo{}
 		for _, pkg := range updates {
-			isMatch, err := h.isPackageOfType(ctx, pkg.Name, updateType)
-			if err != nil {
-				// If we can't determine the type, skip this package
-				continue
-			}
-			if !isMatch {
-				continue
+			if classified[pkg.Name][updateType] {
+				filteredUpdates = append(filteredUpdates, pkg)
 			}
-			filteredUpdates = append(filteredUpdates, pkg)
 		}
 		updates = filteredUpdates
 	}
@@ -645,6 +987,7 @@ func (h *Handler) checkAPTUpdates(ctx context.Context, updateType UpdateType, in
 		AvailableUpdates:      len(updates),
 		PackageDetailsList:     updates,
 		CheckDurationSeconds: time.Since(startTime).Seconds(),
+		keptBack:             sim.keptBack,
 	}
 
 	// Get last apt update time from package lists
@@ -660,3 +1003,15 @@ func (osWrapper) execCommand(ctx context.Context, name string, args ...string) (
 	cmd := exec.CommandContext(ctx, name, args...)
 	return cmd.CombinedOutput()
 }
+
+func (osWrapper) openFile(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (osWrapper) readDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osWrapper) stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
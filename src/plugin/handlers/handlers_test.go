@@ -21,8 +21,11 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/fs"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -132,8 +135,49 @@ func TestEmptyOutput(t *testing.T) {
 	assert.Empty(t, result.PackageDetailsList)
 }
 
+// TestGetUpdateTypeAndFlagsFromExtra ensures item key parameters are mapped to update type and phased flag
+func TestGetUpdateTypeAndFlagsFromExtra(t *testing.T) {
+	tests := []struct {
+		name          string
+		params        []string
+		updateType    UpdateType
+		includePhased bool
+	}{
+		{name: "no parameters", params: nil, updateType: UpdateTypeAll, includePhased: false},
+		{name: "security only", params: []string{"security"}, updateType: UpdateTypeSecurity, includePhased: false},
+		{name: "optional excluding phased", params: []string{"optional", "exclude-phased"}, updateType: UpdateTypeOptional, includePhased: false},
+		{name: "all including phased", params: []string{"all", "include-phased"}, updateType: UpdateTypeAll, includePhased: true},
+		{name: "recommended with empty flag", params: []string{" recommended ", ""}, updateType: UpdateTypeRecommended, includePhased: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updateType, includePhased := getUpdateTypeAndFlagsFromExtra(tt.params)
+			assert.Equal(t, tt.updateType, updateType)
+			assert.Equal(t, tt.includePhased, includePhased)
+		})
+	}
+}
+
+// TestGetUpdateList ensures the list metric returns package names and never null
+func TestGetUpdateList(t *testing.T) {
+	handler := &Handler{
+		sysCalls: newMockSystemCalls("libssl3/noble-updates 3.0.13-0ubuntu3.5]\ncurl/noble-updates 8.5.0-2ubuntu10.6]\n", nil),
+	}
+
+	res, err := handler.GetUpdateList(context.Background(), map[string]string{"type": "all", "phased": "exclude-phased"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"libssl3", "curl"}, res)
+
+	handler.sysCalls = newMockSystemCalls("", nil)
+	res, err = handler.GetUpdateList(context.Background(), map[string]string{"type": "all"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, res)
+}
+
 // mockSystemCalls implements systemCalls interface for testing
 type mockSystemCalls struct {
+	mockFiles
 	output string
 	err    error
 }
@@ -165,6 +209,22 @@ type mockSystemCalls struct {
 
 
 
+// mockFiles implements the file access of systemCalls on top of an in-memory tree.
+// Paths are absolute, as passed by the handlers.
+type mockFiles fstest.MapFS
+
+func (m mockFiles) openFile(name string) (io.ReadCloser, error) {
+	return fstest.MapFS(m).Open(strings.TrimPrefix(name, "/"))
+}
+
+func (m mockFiles) readDir(name string) ([]fs.DirEntry, error) {
+	return fstest.MapFS(m).ReadDir(strings.TrimPrefix(name, "/"))
+}
+
+func (m mockFiles) stat(name string) (fs.FileInfo, error) {
+	return fstest.MapFS(m).Stat(strings.TrimPrefix(name, "/"))
+}
+
 // newMockSystemCalls creates a new mock system calls implementation
 func newMockSystemCalls(output string, err error) systemCalls {
 	return &mockSystemCalls{
@@ -172,3 +232,98 @@ func newMockSystemCalls(output string, err error) systemCalls {
 		err:    err,
 	}
 }
+
+// TestParseInstLine ensures repositories, suites and architecture are parsed from the Inst line tail
+func TestParseInstLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want UpdateInfo
+	}{
+		{
+			name: "upgrade from two pockets",
+			line: "Inst libssl3t64 [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-updates, Ubuntu:24.04/noble-security [amd64])",
+			want: UpdateInfo{
+				Name: "libssl3t64", Current: "3.0.13-0ubuntu3.4", Target: "3.0.13-0ubuntu3.5", Architecture: "amd64",
+				Sources: []InstSource{
+					{Label: "Ubuntu", Version: "24.04", Suite: "noble-updates"},
+					{Label: "Ubuntu", Version: "24.04", Suite: "noble-security"},
+				},
+			},
+		},
+		{
+			name: "foreign architecture",
+			line: "Inst libc6:i386 [2.39-0ubuntu8.3] (2.39-0ubuntu8.4 Ubuntu:24.04/noble-updates [i386]) []",
+			want: UpdateInfo{
+				Name: "libc6:i386", Current: "2.39-0ubuntu8.3", Target: "2.39-0ubuntu8.4", Architecture: "i386",
+				Sources: []InstSource{{Label: "Ubuntu", Version: "24.04", Suite: "noble-updates"}},
+			},
+		},
+		{
+			name: "debian security",
+			line: "Inst openssl [3.0.14-1~deb12u2] (3.0.15-1~deb12u1 Debian-Security:12/stable-security [arm64])",
+			want: UpdateInfo{
+				Name: "openssl", Current: "3.0.14-1~deb12u2", Target: "3.0.15-1~deb12u1", Architecture: "arm64",
+				Sources: []InstSource{{Label: "Debian-Security", Version: "12", Suite: "stable-security"}},
+			},
+		},
+		{
+			name: "label with spaces and no release version",
+			line: "Inst docker-ce [5:27.3.0-1~ubuntu.24.04~noble] (5:27.3.1-1~ubuntu.24.04~noble Docker CE:noble [amd64])",
+			want: UpdateInfo{
+				Name: "docker-ce", Current: "5:27.3.0-1~ubuntu.24.04~noble", Target: "5:27.3.1-1~ubuntu.24.04~noble",
+				Architecture: "amd64", Sources: []InstSource{{Label: "Docker CE", Suite: "noble"}},
+			},
+		},
+		{
+			name: "no release part",
+			line: "Inst nmap (7.80+dfsg1-2ubuntu1.1",
+			want: UpdateInfo{Name: "nmap", Target: "7.80+dfsg1-2ubuntu1.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, ok := parseInstLine(tt.line)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, pkg)
+		})
+	}
+}
+
+// TestClassifyUpdatesFromInstSources ensures updates apt-cache policy knows nothing about are
+// classified from the repositories in the Inst line
+func TestClassifyUpdatesFromInstSources(t *testing.T) {
+	sysCalls := &countingSystemCalls{systemCalls: newMockSystemCalls("", nil)}
+	handler := &Handler{sysCalls: sysCalls}
+
+	security, _ := parseInstLine("Inst openssl [3.0.14-1~deb12u2] (3.0.15-1~deb12u1 Debian-Security:12/stable-security [amd64])")
+	backports, _ := parseInstLine("Inst cockpit [287.1-0+deb12u1] (316-1~bpo12+1 Debian Backports:12-backports/stable-backports [amd64])")
+	updates := []UpdateInfo{security, backports}
+
+	classified := handler.classifyUpdates(context.Background(), updates)
+	assert.Equal(t, 2, sysCalls.calls, "expected apt-cache policy to be asked first")
+	assert.True(t, classified["openssl"][UpdateTypeSecurity])
+	assert.Equal(t, "security-suite", updates[0].SecurityRule)
+	assert.True(t, classified["cockpit"][UpdateTypeOptional])
+	assert.Equal(t, pocketBackports, updates[1].Pocket)
+}
+
+// TestClassifyUpdatesPolicyBeforeInstSources ensures apt-cache policy, which knows the origin and
+// component, is preferred over the Inst line when the repository indexes cannot be read
+func TestClassifyUpdatesPolicyBeforeInstSources(t *testing.T) {
+	handler := &Handler{sysCalls: &policySystemCalls{}}
+
+	htop, _ := parseInstLine("Inst htop [3.3.0-4] (3.3.0-4build1 Ubuntu:24.04/noble-updates [amd64])")
+	updates := []UpdateInfo{htop}
+
+	classified := handler.classifyUpdates(context.Background(), updates)
+
+	// The Inst line does not tell that htop is in universe
+	assert.True(t, classified["htop"][UpdateTypeOptional])
+	assert.False(t, classified["htop"][UpdateTypeRecommended])
+	if assert.Len(t, updates[0].releases, 1) {
+		assert.Equal(t, "Ubuntu", updates[0].releases[0].Origin)
+		assert.Equal(t, "universe", updates[0].releases[0].Component)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"sort"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// HeldPackage is a package on hold, with the candidate the hold keeps back
+type HeldPackage struct {
+	Name            string `json:"name"`
+	Architecture    string `json:"architecture"`
+	Installed       string `json:"installed_version"`
+	Candidate       string `json:"candidate_version,omitempty"`
+	UpdateAvailable bool   `json:"update_available"` // The candidate is newer than the installed version
+	Security        bool   `json:"security"`         // The candidate is a security update
+	SecurityRule    string `json:"security_rule,omitempty"`
+}
+
+// HeldResult is the held packages section of the update information
+type HeldResult struct {
+	Count                int           `json:"count"`
+	UpdatesCount         int           `json:"updates_count"` // Held packages with a newer candidate
+	SecurityUpdatesCount int           `json:"security_updates_count"`
+	List                 []string      `json:"list"`
+	Details              []HeldPackage `json:"details"`
+}
+
+// GetHeldPackages returns the packages on hold and the updates the holds keep back
+func (h *Handler) GetHeldPackages(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	held := &HeldResult{List: []string{}, Details: result.HeldPackagesDetails}
+	if held.Details == nil {
+		held.Details = []HeldPackage{}
+	}
+
+	for _, pkg := range held.Details {
+		held.List = append(held.List, pkg.Name)
+
+		if pkg.UpdateAvailable {
+			held.UpdatesCount++
+		}
+
+		if pkg.Security {
+			held.SecurityUpdatesCount++
+		}
+	}
+
+	held.Count = len(held.Details)
+
+	return held, nil
+}
+
+// heldPackages returns the installed packages on hold in the dpkg selections, which
+// apt-mark hold sets, with their candidates from a single apt-cache policy call.
+// The candidates are classified like regular updates. A package apt-cache
+// prints no policy for is reported without a candidate rather than with the
+// candidate of another architecture.
+func (h *Handler) heldPackages(ctx context.Context, db dpkgDatabase) []HeldPackage {
+	var held []HeldPackage
+
+	for _, entries := range db {
+		for _, pkg := range entries {
+			if pkg.IsHeld() && pkg.IsInstalled() {
+				held = append(held, HeldPackage{Name: pkg.Name, Architecture: pkg.Architecture, Installed: pkg.Version})
+			}
+		}
+	}
+
+	if len(held) == 0 {
+		return []HeldPackage{}
+	}
+
+	sort.Slice(held, func(i, j int) bool {
+		if held[i].Name != held[j].Name {
+			return held[i].Name < held[j].Name
+		}
+
+		return held[i].Architecture < held[j].Architecture
+	})
+
+	// Query every architecture by its qualified name
+	pkgs := make([]InstalledPackage, 0, len(held))
+	names := make([]string, 0, len(held))
+	for _, pkg := range held {
+		installed := InstalledPackage{Name: pkg.Name, Architecture: pkg.Architecture}
+		pkgs = append(pkgs, installed)
+		names = append(names, policyName(installed))
+	}
+
+	policies, err := h.aptCachePolicy(ctx, names)
+	if err != nil {
+		// The holds are still reported, without candidates
+		return held
+	}
+
+	var updates []UpdateInfo
+	for i, policy := range installedPolicies(policies, pkgs) {
+		if policy == nil || policy.Candidate == "" || policy.Candidate == "(none)" {
+			continue
+		}
+
+		held[i].Candidate = policy.Candidate
+		held[i].UpdateAvailable = compareVersions(policy.Candidate, held[i].Installed) > 0
+
+		if held[i].UpdateAvailable {
+			updates = append(updates, UpdateInfo{Name: names[i], Current: held[i].Installed, Target: policy.Candidate})
+		}
+	}
+
+	if len(updates) == 0 {
+		return held
+	}
+
+	classified := h.classifyUpdates(ctx, updates)
+	rules := make(map[string]string, len(updates))
+	for _, update := range updates {
+		rules[update.Name] = update.SecurityRule
+	}
+
+	for i, pkg := range held {
+		if pkg.UpdateAvailable {
+			held[i].Security = classified[names[i]][UpdateTypeSecurity]
+			held[i].SecurityRule = rules[names[i]]
+		}
+	}
+
+	return held
+}
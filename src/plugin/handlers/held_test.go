@@ -0,0 +1,173 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHeldStatus = `Package: openssl
+Status: hold ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.4
+
+Package: htop
+Status: hold ok installed
+Architecture: amd64
+Version: 3.3.0-4build1
+
+Package: curl
+Status: install ok installed
+Architecture: amd64
+Version: 8.5.0-2ubuntu10.6
+
+Package: nano
+Status: hold ok config-files
+Architecture: amd64
+Version: 7.2-2build1
+`
+
+// TestHeldPackages ensures holds are reported with their candidates and whether they keep back a security fix
+func TestHeldPackages(t *testing.T) {
+	handler := &Handler{sysCalls: &policySystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status": {Data: []byte(testHeldStatus)},
+	}}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	held := handler.heldPackages(context.Background(), db)
+	assert.Equal(t, []HeldPackage{
+		{
+			Name:         "htop",
+			Architecture: "amd64",
+			Installed:    "3.3.0-4build1",
+			Candidate:    "3.3.0-4build1",
+		},
+		{
+			Name:            "openssl",
+			Architecture:    "amd64",
+			Installed:       "3.0.13-0ubuntu3.4",
+			Candidate:       "3.0.13-0ubuntu3.5",
+			UpdateAvailable: true,
+			Security:        true,
+			SecurityRule:    "ubuntu-security",
+		},
+	}, held)
+}
+
+// TestGetHeldPackages ensures the held packages item summarizes the snapshot
+func TestGetHeldPackages(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{"var/lib/dpkg/status": {Data: []byte(testHeldStatus)}},
+		output:    "curl/noble-updates 8.5.0-2ubuntu10.7]\n",
+	}}
+
+	res, err := handler.GetHeldPackages(context.Background(), nil)
+	require.NoError(t, err)
+
+	held := res.(*HeldResult)
+	assert.Equal(t, 2, held.Count)
+	assert.Equal(t, []string{"htop", "openssl"}, held.List)
+
+	// The mock prints no policy for the held packages
+	assert.Equal(t, 0, held.UpdatesCount)
+	assert.Equal(t, 0, held.SecurityUpdatesCount)
+}
+
+const testHeldMultiarchStatus = `Package: libssl3t64
+Status: hold ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.4
+
+Package: libssl3t64
+Status: hold ok installed
+Architecture: i386
+Version: 3.0.13-0ubuntu3.4
+
+Package: libgcc-s1
+Status: hold ok installed
+Architecture: i386
+Version: 14-20240412-0ubuntu1
+`
+
+// apt-cache prints the native package under its bare name and knows no i386 libgcc-s1
+const testHeldMultiarchPolicy = `libssl3t64:
+  Installed: 3.0.13-0ubuntu3.4
+  Candidate: 3.0.13-0ubuntu3.5
+  Version table:
+     3.0.13-0ubuntu3.5 500
+        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+ *** 3.0.13-0ubuntu3.4 100
+        100 /var/lib/dpkg/status
+libssl3t64:i386:
+  Installed: 3.0.13-0ubuntu3.4
+  Candidate: 3.0.13-0ubuntu3.4
+  Version table:
+ *** 3.0.13-0ubuntu3.4 100
+        100 /var/lib/dpkg/status
+`
+
+type multiarchSystemCalls struct {
+	mockFiles
+	queried []string
+}
+
+func (m *multiarchSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if strings.Join(args, " ") == "LC_ALL=C LANG=C apt-cache policy" {
+		return []byte(testSourcesPolicyOutput), nil
+	}
+
+	m.queried = append(m.queried, args[4:]...)
+
+	return []byte(testHeldMultiarchPolicy), nil
+}
+
+// TestHeldPackagesMultiarch ensures every architecture gets its own candidate and
+// a foreign package without a policy is not given the native package's candidate
+func TestHeldPackagesMultiarch(t *testing.T) {
+	sysCalls := &multiarchSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status": {Data: []byte(testHeldMultiarchStatus)},
+	}}
+	handler := &Handler{sysCalls: sysCalls}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	held := handler.heldPackages(context.Background(), db)
+	require.Len(t, held, 3)
+	assert.Equal(t, []string{"libgcc-s1:i386", "libssl3t64:amd64", "libssl3t64:i386"}, sysCalls.queried[:3])
+
+	assert.Equal(t, "libgcc-s1", held[0].Name)
+	assert.Empty(t, held[0].Candidate)
+	assert.False(t, held[0].UpdateAvailable)
+
+	assert.Equal(t, "amd64", held[1].Architecture)
+	assert.Equal(t, "3.0.13-0ubuntu3.5", held[1].Candidate)
+	assert.True(t, held[1].UpdateAvailable)
+
+	assert.Equal(t, "i386", held[2].Architecture)
+	assert.Equal(t, "3.0.13-0ubuntu3.4", held[2].Candidate)
+	assert.False(t, held[2].UpdateAvailable)
+}
@@ -0,0 +1,556 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// aptLogDir holds history.log, rotated monthly to history.log.<n>.gz
+	aptLogDir = "/var/log/apt"
+	// aptHistoryLog records every apt transaction with its command line and requester
+	aptHistoryLog = "history.log"
+	// dpkgLogDir holds dpkg.log, which also records packages installed with dpkg directly
+	dpkgLogDir = "/var/log"
+	// dpkgLog is the dpkg action log
+	dpkgLog = "dpkg.log"
+
+	// historyTimeLayout is the timestamp format of both logs; history.log separates
+	// date and time with two spaces, which is normalized before parsing
+	historyTimeLayout = "2006-01-02 15:04:05"
+
+	// maxHistoryTransactions bounds the transactions reported by updates.history
+	maxHistoryTransactions = 20
+	// maxLatencySamples bounds the install latencies the mean is computed from
+	maxLatencySamples = 500
+)
+
+// sectionHistory names the package change history in AllUpdatesResult.SectionErrors
+const sectionHistory = "history"
+
+// Sources of history events
+const (
+	historySourceApt  = "apt"
+	historySourceDpkg = "dpkg"
+)
+
+// HistoryStatus summarizes the package changes recorded by apt and dpkg
+type HistoryStatus struct {
+	LastUpgrade  *HistoryEvent        `json:"last_upgrade"`
+	LastInstall  *HistoryEvent        `json:"last_install"`
+	LastRemoval  *HistoryEvent        `json:"last_removal"`
+	Transactions []HistoryTransaction `json:"transactions"` // Most recent apt transactions, newest first
+
+	// Mean time between an update first showing up as pending and its installation,
+	// over the updates the plugin has seen pending since it started
+	MeanTimeToInstallSeconds float64 `json:"mean_time_to_install_seconds"`
+	InstallLatencySamples    int     `json:"install_latency_samples"`
+
+	// LogErrors lists why one of the logs could not be read, the other one is still reported
+	LogErrors []string `json:"log_errors,omitempty"`
+}
+
+// HistoryEvent is the last transaction that upgraded, installed or removed packages
+type HistoryEvent struct {
+	Time        int64    `json:"time"`         // Unix timestamp the transaction finished
+	Source      string   `json:"source"`       // apt, or dpkg for packages handled by dpkg directly
+	Command     string   `json:"command"`      // Command line, empty for dpkg
+	RequestedBy string   `json:"requested_by"` // User who ran the command, empty when run by root or a service
+	Packages    []string `json:"packages"`
+}
+
+// time returns the time of the event, 0 if there is none
+func (e *HistoryEvent) time() int64 {
+	if e == nil {
+		return 0
+	}
+
+	return e.Time
+}
+
+// HistoryTransaction is an apt transaction of history.log or a dpkg run of dpkg.log
+type HistoryTransaction struct {
+	Start       int64    `json:"start"`
+	End         int64    `json:"end"`
+	Source      string   `json:"source"`
+	Command     string   `json:"command"`
+	RequestedBy string   `json:"requested_by"`
+	Upgraded    []string `json:"upgraded"`
+	Installed   []string `json:"installed"`
+	Removed     []string `json:"removed"`
+	Error       string   `json:"error,omitempty"`
+
+	// versions maps the upgraded and installed package names, without architecture, to their new versions
+	versions map[string]string
+}
+
+// installTracker remembers when pending updates were first seen to measure how long they take to be installed
+type installTracker struct {
+	mu        sync.Mutex
+	firstSeen map[string]time.Time // Keyed by package name and target version, see latencyKey
+	samples   []time.Duration
+}
+
+// GetHistory returns the last upgrade, install and removal and the recent apt transactions
+func (h *Handler) GetHistory(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.history == nil {
+		return nil, result.sectionError(sectionHistory, "failed to read the apt and dpkg logs")
+	}
+
+	return result.history, nil
+}
+
+// historyStatus reads the apt and dpkg logs and records the install latency of the
+// updates that are no longer pending. A log that cannot be read is listed in
+// LogErrors and the history is taken from the other one; it fails only when
+// neither log can be read.
+func (h *Handler) historyStatus(now time.Time, pending []UpdateInfo) (*HistoryStatus, error) {
+	var logErrs []error
+
+	aptLogs, err := h.readRotatedLogs(aptLogDir, aptHistoryLog)
+	if err != nil {
+		logErrs = append(logErrs, err)
+	}
+
+	dpkgLogs, err := h.readRotatedLogs(dpkgLogDir, dpkgLog)
+	if err != nil {
+		logErrs = append(logErrs, err)
+	}
+
+	if len(logErrs) == 2 {
+		return nil, errors.Join(logErrs...)
+	}
+
+	var aptTransactions, dpkgTransactions []HistoryTransaction
+	for _, data := range aptLogs {
+		aptTransactions = append(aptTransactions, parseAptHistory(data)...)
+	}
+
+	for _, data := range dpkgLogs {
+		dpkgTransactions = append(dpkgTransactions, parseDpkgLog(data)...)
+	}
+
+	sortTransactions(aptTransactions)
+	sortTransactions(dpkgTransactions)
+
+	status := &HistoryStatus{Transactions: aptTransactions[:min(len(aptTransactions), maxHistoryTransactions)]}
+	if status.Transactions == nil {
+		status.Transactions = []HistoryTransaction{}
+	}
+
+	for _, err := range logErrs {
+		status.LogErrors = append(status.LogErrors, err.Error())
+	}
+
+	status.LastUpgrade = lastEvent(aptTransactions, dpkgTransactions, func(t HistoryTransaction) []string { return t.Upgraded })
+	status.LastInstall = lastEvent(aptTransactions, dpkgTransactions, func(t HistoryTransaction) []string { return t.Installed })
+	status.LastRemoval = lastEvent(aptTransactions, dpkgTransactions, func(t HistoryTransaction) []string { return t.Removed })
+
+	installed := make(map[string]time.Time)
+	for _, transactions := range [][]HistoryTransaction{aptTransactions, dpkgTransactions} {
+		for _, t := range transactions {
+			for name, version := range t.versions {
+				key := latencyKey(name, version)
+				if at, ok := installed[key]; !ok || t.End < at.Unix() {
+					installed[key] = time.Unix(t.End, 0)
+				}
+			}
+		}
+	}
+
+	mean, samples := h.installs.track(now, pending, installed)
+	status.MeanTimeToInstallSeconds = mean.Seconds()
+	status.InstallLatencySamples = samples
+
+	return status, nil
+}
+
+// lastEvent returns the most recent transaction changing packages of one kind. dpkg
+// runs are only reported when they are newer than the apt transaction, as apt
+// transactions show up in dpkg.log as well.
+func lastEvent(aptTransactions, dpkgTransactions []HistoryTransaction, packages func(HistoryTransaction) []string) *HistoryEvent {
+	var event *HistoryEvent
+
+	for _, t := range aptTransactions {
+		if len(packages(t)) > 0 {
+			event = &HistoryEvent{Time: t.End, Source: t.Source, Command: t.Command, RequestedBy: t.RequestedBy, Packages: packages(t)}
+
+			break
+		}
+	}
+
+	for _, t := range dpkgTransactions {
+		if len(packages(t)) == 0 {
+			continue
+		}
+
+		if event == nil || t.End > event.Time {
+			event = &HistoryEvent{Time: t.End, Source: t.Source, Packages: packages(t)}
+		}
+
+		break
+	}
+
+	return event
+}
+
+// sortTransactions orders transactions newest first
+func sortTransactions(transactions []HistoryTransaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Start > transactions[j].Start
+	})
+}
+
+// readRotatedLogs returns the contents of a log file and its rotated copies
+// (<name>.1, <name>.2.gz, ...), decompressing them as needed
+func (h *Handler) readRotatedLogs(dir, name string) ([][]byte, error) {
+	entries, err := h.sysCalls.readDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Nothing was ever logged, e.g. in a minimal container image
+			return nil, nil
+		}
+
+		return nil, errs.Wrapf(err, "failed to list %s", dir)
+	}
+
+	var logs [][]byte
+	for _, entry := range entries {
+		if entry.IsDir() || !isRotatedLog(entry.Name(), name) {
+			continue
+		}
+
+		data, err := h.readLog(path.Join(dir, entry.Name()))
+		if err != nil {
+			// Rotated away in the meantime
+			continue
+		}
+
+		logs = append(logs, data)
+	}
+
+	return logs, nil
+}
+
+// isRotatedLog reports whether file is the log name or one of its rotated copies
+func isRotatedLog(file, name string) bool {
+	if file == name {
+		return true
+	}
+
+	suffix, ok := strings.CutPrefix(file, name+".")
+	if !ok {
+		return false
+	}
+
+	_, err := strconv.Atoi(strings.TrimSuffix(suffix, ".gz"))
+
+	return err == nil
+}
+
+// readLog reads a plain or gzip compressed log file
+func (h *Handler) readLog(name string) ([]byte, error) {
+	if path.Ext(name) != ".gz" {
+		return h.readFile(name)
+	}
+
+	f, err := h.sysCalls.openFile(name)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to open file")
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to decompress gzip")
+	}
+	defer gz.Close() //nolint:errcheck // read-only stream
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to read %s", name)
+	}
+
+	return data, nil
+}
+
+// parseAptHistory parses the transactions of an apt history log:
+//
+//	Start-Date: 2026-10-15  06:25:15
+//	Commandline: apt-get upgrade
+//	Requested-By: alice (1000)
+//	Upgrade: openssl:amd64 (3.0.13-0ubuntu3.4, 3.0.13-0ubuntu3.5)
+//	End-Date: 2026-10-15  06:25:40
+func parseAptHistory(data []byte) []HistoryTransaction {
+	var transactions []HistoryTransaction
+
+	//nolint:errcheck // the callback never fails
+	parseDeb822(bytes.NewReader(data), func(stanza deb822Stanza) error {
+		start, ok := parseHistoryTime(stanza["Start-Date"])
+		if !ok {
+			return nil
+		}
+
+		end, ok := parseHistoryTime(stanza["End-Date"])
+		if !ok {
+			// Interrupted transaction
+			end = start
+		}
+
+		t := HistoryTransaction{
+			Start:     start,
+			End:       end,
+			Source:    historySourceApt,
+			Command:   stanza["Commandline"],
+			Error:     stanza["Error"],
+			Upgraded:  []string{},
+			Installed: []string{},
+			Removed:   []string{},
+			versions:  make(map[string]string),
+		}
+
+		// Requested-By: <user> (<uid>)
+		t.RequestedBy, _, _ = strings.Cut(stanza["Requested-By"], " (")
+
+		for _, field := range []string{"Upgrade", "Downgrade", "Reinstall"} {
+			t.Upgraded = append(t.Upgraded, t.addPackages(stanza[field], true)...)
+		}
+
+		t.Installed = append(t.Installed, t.addPackages(stanza["Install"], false)...)
+
+		for _, field := range []string{"Remove", "Purge"} {
+			for _, pkg := range parseHistoryPackages(stanza[field]) {
+				t.Removed = append(t.Removed, pkg.name)
+			}
+		}
+
+		transactions = append(transactions, t)
+
+		return nil
+	})
+
+	return transactions
+}
+
+// addPackages records the new versions of a history.log package list and returns
+// the package names. Upgrades list the old and the new version, installs the new
+// version optionally followed by "automatic".
+func (t *HistoryTransaction) addPackages(value string, upgrade bool) []string {
+	var names []string
+
+	for _, pkg := range parseHistoryPackages(value) {
+		names = append(names, pkg.name)
+
+		version := ""
+		switch {
+		case upgrade && len(pkg.versions) > 1:
+			version = pkg.versions[1]
+		case !upgrade && len(pkg.versions) > 0:
+			version = pkg.versions[0]
+		}
+
+		if version != "" {
+			name, _, _ := strings.Cut(pkg.name, ":")
+			t.versions[name] = version
+		}
+	}
+
+	return names
+}
+
+// historyPackage is an entry of a history.log package list
+type historyPackage struct {
+	name     string
+	versions []string
+}
+
+// parseHistoryPackages parses "libssl3t64:amd64 (3.0.13-0ubuntu3.4, 3.0.13-0ubuntu3.5), htop:amd64 (3.3.0-4)"
+func parseHistoryPackages(value string) []historyPackage {
+	var packages []historyPackage
+
+	for value != "" {
+		name, rest, _ := strings.Cut(value, " (")
+		versions, next, _ := strings.Cut(rest, ")")
+		value = strings.TrimPrefix(strings.TrimSpace(next), ", ")
+
+		pkg := historyPackage{name: strings.TrimSpace(name)}
+		for _, version := range strings.Split(versions, ",") {
+			pkg.versions = append(pkg.versions, strings.TrimSpace(version))
+		}
+
+		if pkg.name != "" {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return packages
+}
+
+// parseDpkgLog groups the actions of a dpkg log into one transaction per dpkg run:
+//
+//	2026-10-15 06:25:20 startup archives unpack
+//	2026-10-15 06:25:20 upgrade openssl:amd64 3.0.13-0ubuntu3.4 3.0.13-0ubuntu3.5
+//	2026-10-15 06:25:21 install htop:amd64 <none> 3.3.0-4
+//	2026-10-15 06:25:22 remove nano:amd64 7.2-2 <none>
+func parseDpkgLog(data []byte) []HistoryTransaction {
+	var (
+		transactions []HistoryTransaction
+		current      *HistoryTransaction
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		at, ok := parseHistoryTime(fields[0] + " " + fields[1])
+		if !ok {
+			continue
+		}
+
+		if fields[2] == "startup" || current == nil {
+			transactions = append(transactions, HistoryTransaction{
+				Start:     at,
+				End:       at,
+				Source:    historySourceDpkg,
+				Upgraded:  []string{},
+				Installed: []string{},
+				Removed:   []string{},
+				versions:  make(map[string]string),
+			})
+			current = &transactions[len(transactions)-1]
+
+			if fields[2] == "startup" {
+				continue
+			}
+		}
+
+		current.End = at
+
+		// <action> <package> <old version> <new version>
+		if len(fields) < 6 {
+			continue
+		}
+
+		action, pkg, version := fields[2], fields[3], fields[5]
+		name, _, _ := strings.Cut(pkg, ":")
+
+		switch action {
+		case "upgrade":
+			current.Upgraded = append(current.Upgraded, pkg)
+			current.versions[name] = version
+		case "install":
+			current.Installed = append(current.Installed, pkg)
+			current.versions[name] = version
+		case "remove", "purge":
+			current.Removed = append(current.Removed, pkg)
+		}
+	}
+
+	return transactions
+}
+
+// parseHistoryTime parses a log timestamp in local time, see historyTimeLayout
+func parseHistoryTime(value string) (int64, bool) {
+	t, err := time.ParseInLocation(historyTimeLayout, strings.Join(strings.Fields(value), " "), time.Local)
+	if err != nil {
+		return 0, false
+	}
+
+	return t.Unix(), true
+}
+
+// latencyKey identifies a version of a package across Inst lines and logs
+func latencyKey(name, version string) string {
+	name, _, _ = strings.Cut(name, ":")
+
+	return name + "=" + version
+}
+
+// track records when the pending updates were first seen and, for the updates that
+// are no longer pending, how long they took to be installed. It returns the mean
+// latency and the number of latencies it is computed from.
+func (t *installTracker) track(now time.Time, pending []UpdateInfo, installed map[string]time.Time) (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.firstSeen == nil {
+		t.firstSeen = make(map[string]time.Time)
+	}
+
+	current := make(map[string]bool, len(pending))
+	for _, pkg := range pending {
+		key := latencyKey(pkg.Name, pkg.Target)
+		current[key] = true
+
+		if _, ok := t.firstSeen[key]; !ok {
+			t.firstSeen[key] = now
+		}
+	}
+
+	for key, seen := range t.firstSeen {
+		if current[key] {
+			continue
+		}
+
+		// Superseded by a newer version or installed
+		delete(t.firstSeen, key)
+
+		if at, ok := installed[key]; ok && !at.Before(seen) {
+			t.samples = append(t.samples, at.Sub(seen))
+		}
+	}
+
+	if len(t.samples) > maxLatencySamples {
+		t.samples = t.samples[len(t.samples)-maxLatencySamples:]
+	}
+
+	if len(t.samples) == 0 {
+		return 0, 0
+	}
+
+	var total time.Duration
+	for _, sample := range t.samples {
+		total += sample
+	}
+
+	return total / time.Duration(len(t.samples)), len(t.samples)
+}
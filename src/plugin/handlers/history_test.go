@@ -0,0 +1,219 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAptHistory = `
+Start-Date: 2026-10-14  09:02:11
+Commandline: apt-get install htop
+Requested-By: alice (1000)
+Install: htop:amd64 (3.3.0-4), libnl-genl-3-200:amd64 (3.7.0-0.3build1.1, automatic)
+End-Date: 2026-10-14  09:02:14
+
+Start-Date: 2026-10-15  06:25:15
+Commandline: /usr/bin/unattended-upgrade
+Upgrade: openssl:amd64 (3.0.13-0ubuntu3.4, 3.0.13-0ubuntu3.5), libssl3t64:amd64 (3.0.13-0ubuntu3.4, 3.0.13-0ubuntu3.5)
+End-Date: 2026-10-15  06:25:40
+
+Start-Date: 2026-10-15  10:11:00
+Commandline: apt purge nano
+Requested-By: bob (1001)
+Purge: nano:amd64 (7.2-2)
+End-Date: 2026-10-15  10:11:02
+`
+
+const testOldAptHistory = `
+Start-Date: 2026-09-01  08:00:00
+Commandline: apt-get -y upgrade
+Upgrade: curl:amd64 (8.5.0-2ubuntu10.3, 8.5.0-2ubuntu10.4)
+End-Date: 2026-09-01  08:00:30
+`
+
+const testDpkgLog = `2026-10-15 06:25:20 startup archives unpack
+2026-10-15 06:25:20 upgrade openssl:amd64 3.0.13-0ubuntu3.4 3.0.13-0ubuntu3.5
+2026-10-15 06:25:21 status half-configured openssl:amd64 3.0.13-0ubuntu3.5
+2026-10-15 06:25:22 upgrade libssl3t64:amd64 3.0.13-0ubuntu3.4 3.0.13-0ubuntu3.5
+2026-10-15 10:11:01 startup packages remove
+2026-10-15 10:11:01 remove nano:amd64 7.2-2 <none>
+2026-10-16 07:40:05 startup archives unpack
+2026-10-16 07:40:05 install vendor-agent:amd64 <none> 2.1.0
+2026-10-16 07:40:06 status installed vendor-agent:amd64 2.1.0
+`
+
+// localTime returns the Unix timestamp of a log timestamp in local time
+func localTime(t *testing.T, value string) int64 {
+	t.Helper()
+
+	at, ok := parseHistoryTime(value)
+	require.True(t, ok)
+
+	return at
+}
+
+// TestParseAptHistory ensures transactions, requesters and package lists are parsed from history.log
+func TestParseAptHistory(t *testing.T) {
+	transactions := parseAptHistory([]byte(testAptHistory))
+	require.Len(t, transactions, 3)
+
+	install := transactions[0]
+	assert.Equal(t, localTime(t, "2026-10-14 09:02:11"), install.Start)
+	assert.Equal(t, localTime(t, "2026-10-14 09:02:14"), install.End)
+	assert.Equal(t, "apt-get install htop", install.Command)
+	assert.Equal(t, "alice", install.RequestedBy)
+	assert.Equal(t, []string{"htop:amd64", "libnl-genl-3-200:amd64"}, install.Installed)
+	assert.Equal(t, map[string]string{"htop": "3.3.0-4", "libnl-genl-3-200": "3.7.0-0.3build1.1"}, install.versions)
+
+	upgrade := transactions[1]
+	assert.Empty(t, upgrade.RequestedBy)
+	assert.Equal(t, []string{"openssl:amd64", "libssl3t64:amd64"}, upgrade.Upgraded)
+	assert.Equal(t, "3.0.13-0ubuntu3.5", upgrade.versions["openssl"])
+
+	assert.Equal(t, []string{"nano:amd64"}, transactions[2].Removed)
+
+	// Empty package lists are serialized as arrays rather than null
+	data, err := json.Marshal(install)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"upgraded":[]`)
+	assert.Contains(t, string(data), `"removed":[]`)
+}
+
+// TestParseDpkgLog ensures dpkg actions are grouped by dpkg run
+func TestParseDpkgLog(t *testing.T) {
+	transactions := parseDpkgLog([]byte(testDpkgLog))
+	require.Len(t, transactions, 3)
+
+	assert.Equal(t, localTime(t, "2026-10-15 06:25:20"), transactions[0].Start)
+	assert.Equal(t, localTime(t, "2026-10-15 06:25:22"), transactions[0].End)
+	assert.Equal(t, []string{"openssl:amd64", "libssl3t64:amd64"}, transactions[0].Upgraded)
+	assert.Equal(t, []string{"nano:amd64"}, transactions[1].Removed)
+	assert.Empty(t, transactions[1].Installed)
+	assert.NotNil(t, transactions[1].Installed)
+	assert.Equal(t, []string{"vendor-agent:amd64"}, transactions[2].Installed)
+	assert.Equal(t, map[string]string{"vendor-agent": "2.1.0"}, transactions[2].versions)
+}
+
+// TestHistoryStatus ensures the last events are taken from history.log, its rotated copies
+// and dpkg.log, and that install latencies are measured
+func TestHistoryStatus(t *testing.T) {
+	var rotated bytes.Buffer
+	gz := gzip.NewWriter(&rotated)
+	_, err := gz.Write([]byte(testOldAptHistory))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/log/apt/history.log":      {Data: []byte(testAptHistory)},
+		"var/log/apt/history.log.1.gz": {Data: rotated.Bytes()},
+		"var/log/apt/term.log":         {Data: []byte("Log started\n")},
+		"var/log/dpkg.log":             {Data: []byte(testDpkgLog)},
+		"var/log/syslog":               {Data: []byte{}},
+	}}}
+
+	seen := time.Unix(localTime(t, "2026-10-15 00:25:40"), 0)
+	pending := []UpdateInfo{{Name: "openssl", Target: "3.0.13-0ubuntu3.5"}, {Name: "vim", Target: "2:9.1.0016-1ubuntu7.4"}}
+
+	status, err := handler.historyStatus(seen, pending)
+	require.NoError(t, err)
+	assert.Zero(t, status.InstallLatencySamples)
+
+	// An apt transaction
+	require.NotNil(t, status.LastUpgrade)
+	assert.Equal(t, HistoryEvent{
+		Time:     localTime(t, "2026-10-15 06:25:40"),
+		Source:   historySourceApt,
+		Command:  "/usr/bin/unattended-upgrade",
+		Packages: []string{"openssl:amd64", "libssl3t64:amd64"},
+	}, *status.LastUpgrade)
+
+	// Installed with dpkg -i after the last apt transaction
+	require.NotNil(t, status.LastInstall)
+	assert.Equal(t, HistoryEvent{
+		Time:     localTime(t, "2026-10-16 07:40:06"),
+		Source:   historySourceDpkg,
+		Packages: []string{"vendor-agent:amd64"},
+	}, *status.LastInstall)
+
+	// The dpkg run of the apt transaction is not newer
+	require.NotNil(t, status.LastRemoval)
+	assert.Equal(t, "bob", status.LastRemoval.RequestedBy)
+
+	require.Len(t, status.Transactions, 4)
+	assert.Equal(t, "apt purge nano", status.Transactions[0].Command)
+	assert.Equal(t, "apt-get -y upgrade", status.Transactions[3].Command)
+
+	// openssl was unpacked by dpkg at 06:25:22, vim is still pending
+	status, err = handler.historyStatus(seen.Add(12*time.Hour), pending[1:])
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.InstallLatencySamples)
+	assert.InDelta(t, (6*time.Hour - 18*time.Second).Seconds(), status.MeanTimeToInstallSeconds, 0.001)
+}
+
+// TestHistoryStatusDegraded ensures a log that cannot be read leaves the other one reported,
+// and the cause is returned when neither can be read
+func TestHistoryStatusDegraded(t *testing.T) {
+	// /var/log/apt cannot be listed
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/log/apt":      {Data: []byte{}},
+		"var/log/dpkg.log": {Data: []byte(testDpkgLog)},
+	}}}
+
+	status, err := handler.historyStatus(time.Now(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, status.LastInstall)
+	assert.Equal(t, historySourceDpkg, status.LastInstall.Source)
+	require.Len(t, status.LogErrors, 1)
+	assert.Contains(t, status.LogErrors[0], "failed to list /var/log/apt")
+
+	// Without /var/log/apt nothing was ever logged by apt
+	handler.sysCalls = &mockSystemCalls{mockFiles: mockFiles{"var/log/dpkg.log": {Data: []byte(testDpkgLog)}}}
+	status, err = handler.historyStatus(time.Now(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, status.LogErrors)
+
+	// Neither directory can be listed
+	handler.sysCalls = &mockSystemCalls{mockFiles: mockFiles{"var/log": {Data: []byte{}}, "var/log/apt": {Data: []byte{}}}}
+	_, err = handler.GetHistory(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list /var/log")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.(*AllUpdatesResult).SectionErrors, "history")
+}
+
+// TestIsRotatedLog ensures only the log and its numbered copies are read
+func TestIsRotatedLog(t *testing.T) {
+	assert.True(t, isRotatedLog("history.log", "history.log"))
+	assert.True(t, isRotatedLog("history.log.1", "history.log"))
+	assert.True(t, isRotatedLog("history.log.12.gz", "history.log"))
+	assert.False(t, isRotatedLog("history.log.old", "history.log"))
+	assert.False(t, isRotatedLog("eipp.log.xz", "history.log"))
+	assert.False(t, isRotatedLog("dpkg.log", "history.log"))
+}
@@ -0,0 +1,188 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// kernelReleaseFile holds the release of the running kernel, as printed by uname -r
+const kernelReleaseFile = "/proc/sys/kernel/osrelease"
+
+// kernelImagePrefixes are the name prefixes of kernel image packages, followed by the kernel release
+//
+//nolint:gochecknoglobals // lookup table.
+var kernelImagePrefixes = []string{"linux-image-unsigned-", "linux-image-"}
+
+// kernelMetaPrefixes are the name prefixes of the meta packages pulling in new kernels,
+// e.g. linux-generic, linux-image-generic-hwe-24.04 or linux-image-amd64
+//
+//nolint:gochecknoglobals // lookup table.
+var kernelMetaPrefixes = []string{"linux-image-", "linux-generic", "linux-virtual", "linux-lowlatency", "linux-aws",
+	"linux-azure", "linux-gcp", "linux-oracle", "linux-kvm", "linux-raspi"}
+
+// sectionKernel names the kernel status in AllUpdatesResult.SectionErrors
+const sectionKernel = "kernel"
+
+// KernelPackage is an installed kernel image
+type KernelPackage struct {
+	Package string `json:"package"`
+	Release string `json:"release"` // Kernel release, as printed by uname -r
+	Version string `json:"version"` // Package version
+}
+
+// KernelStatus compares the running kernel with the installed and pending ones
+type KernelStatus struct {
+	Running        string          `json:"running"`
+	Latest         string          `json:"latest_installed"` // Release of the newest installed kernel
+	NewerInstalled bool            `json:"newer_installed"`  // A newer kernel is installed but not booted
+	UpdatePending  bool            `json:"update_pending"`   // A kernel update is among the pending updates
+	PendingUpdates []string        `json:"pending_updates"`
+	Installed      []KernelPackage `json:"installed"` // Oldest first
+	Removable      []string        `json:"removable"` // Old kernel packages apt would not keep
+}
+
+// GetKernelStatus returns the running kernel compared with the installed and pending kernels
+func (h *Handler) GetKernelStatus(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.Kernel == nil {
+		return nil, result.sectionError(sectionKernel, "failed to check kernel status")
+	}
+
+	return result.Kernel, nil
+}
+
+// kernelStatus reads the running kernel, takes the installed kernel images from
+// the dpkg database and finds the kernel packages among the pending updates
+func (h *Handler) kernelStatus(db dpkgDatabase, updates []UpdateInfo) (*KernelStatus, error) {
+	data, err := h.readFile(kernelReleaseFile)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read running kernel release")
+	}
+
+	status := &KernelStatus{
+		Running:        strings.TrimSpace(string(data)),
+		PendingUpdates: []string{},
+		Installed:      installedKernels(db),
+		Removable:      []string{},
+	}
+
+	for _, pkg := range updates {
+		if isKernelPackage(pkg.Name) {
+			status.UpdatePending = true
+			status.PendingUpdates = append(status.PendingUpdates, pkg.Name)
+		}
+	}
+
+	if len(status.Installed) == 0 {
+		// No kernel managed by dpkg, e.g. in a container
+		return status, nil
+	}
+
+	latest := status.Installed[len(status.Installed)-1]
+	status.Latest = latest.Release
+
+	running := slices.IndexFunc(status.Installed, func(k KernelPackage) bool { return k.Release == status.Running })
+	if running >= 0 {
+		status.NewerInstalled = running < len(status.Installed)-1 &&
+			compareVersions(latest.Version, status.Installed[running].Version) > 0
+	} else {
+		status.NewerInstalled = compareVersions(latest.Release, status.Running) > 0
+	}
+
+	// Like apt's kernel autoremoval, keep the running, the latest and the previous kernel
+	for i, kernel := range status.Installed {
+		if i >= len(status.Installed)-2 || i == running {
+			continue
+		}
+
+		status.Removable = append(status.Removable, kernel.Package)
+	}
+
+	return status, nil
+}
+
+// installedKernels returns the installed kernel images, sorted oldest first
+func installedKernels(db dpkgDatabase) []KernelPackage {
+	kernels := []KernelPackage{}
+
+	for name, entries := range db {
+		release := kernelRelease(name)
+		if release == "" {
+			continue
+		}
+
+		for _, pkg := range entries {
+			if pkg.IsInstalled() {
+				kernels = append(kernels, KernelPackage{Package: name, Release: release, Version: pkg.Version})
+
+				break
+			}
+		}
+	}
+
+	slices.SortFunc(kernels, func(a, b KernelPackage) int {
+		if c := compareVersions(a.Version, b.Version); c != 0 {
+			return c
+		}
+
+		if c := compareVersions(a.Release, b.Release); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Package, b.Package)
+	})
+
+	// Signed and unsigned images of the same release are the same kernel
+	return slices.CompactFunc(kernels, func(a, b KernelPackage) bool { return a.Release == b.Release })
+}
+
+// kernelRelease returns the kernel release of a kernel image package, or an empty
+// string for other packages and meta packages such as linux-image-generic
+func kernelRelease(name string) string {
+	for _, prefix := range kernelImagePrefixes {
+		release, ok := strings.CutPrefix(name, prefix)
+		if ok && release != "" && isDigit(release[0]) {
+			return release
+		}
+	}
+
+	return ""
+}
+
+// isKernelPackage reports whether an update installs a new kernel image
+func isKernelPackage(name string) bool {
+	name, _, _ = strings.Cut(name, ":")
+
+	for _, prefix := range kernelMetaPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,173 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKernelStatus = `Package: linux-image-6.8.0-45-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-45.45
+
+Package: linux-image-6.8.0-49-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-49.49
+
+Package: linux-image-6.8.0-51-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-51.52
+
+Package: linux-image-unsigned-6.8.0-51-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-51.52
+
+Package: linux-image-6.8.0-41-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-41.41
+
+Package: linux-image-6.8.0-38-generic
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 6.8.0-38.38
+
+Package: linux-image-generic
+Status: install ok installed
+Architecture: amd64
+Version: 6.8.0-51.52
+
+Package: linux-firmware
+Status: install ok installed
+Architecture: all
+Version: 20240318.git3b128b60-0ubuntu2.5
+`
+
+// TestKernelStatus ensures a newer installed kernel, pending kernel updates and removable kernels are reported
+func TestKernelStatus(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"proc/sys/kernel/osrelease": {Data: []byte("6.8.0-45-generic\n")},
+		"var/lib/dpkg/status":       {Data: []byte(testKernelStatus)},
+	}}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	status, err := handler.kernelStatus(db, []UpdateInfo{
+		{Name: "curl", Target: "8.5.0-2ubuntu10.6"},
+		{Name: "linux-generic", Target: "6.8.0-52.53"},
+		{Name: "linux-image-generic", Target: "6.8.0-52.53"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "6.8.0-45-generic", status.Running)
+	assert.Equal(t, "6.8.0-51-generic", status.Latest)
+	assert.True(t, status.NewerInstalled)
+	assert.True(t, status.UpdatePending)
+	assert.Equal(t, []string{"linux-generic", "linux-image-generic"}, status.PendingUpdates)
+	assert.Equal(t, []KernelPackage{
+		{Package: "linux-image-6.8.0-41-generic", Release: "6.8.0-41-generic", Version: "6.8.0-41.41"},
+		{Package: "linux-image-6.8.0-45-generic", Release: "6.8.0-45-generic", Version: "6.8.0-45.45"},
+		{Package: "linux-image-6.8.0-49-generic", Release: "6.8.0-49-generic", Version: "6.8.0-49.49"},
+		{Package: "linux-image-6.8.0-51-generic", Release: "6.8.0-51-generic", Version: "6.8.0-51.52"},
+	}, status.Installed)
+
+	// The running, the latest and the previous kernel are kept
+	assert.Equal(t, []string{"linux-image-6.8.0-41-generic"}, status.Removable)
+}
+
+// TestKernelStatusLatestRunning ensures no reboot is reported when the newest kernel is running
+func TestKernelStatusLatestRunning(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"proc/sys/kernel/osrelease": {Data: []byte("6.8.0-51-generic\n")},
+		"var/lib/dpkg/status":       {Data: []byte(testKernelStatus)},
+	}}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	status, err := handler.kernelStatus(db, nil)
+	require.NoError(t, err)
+	assert.False(t, status.NewerInstalled)
+	assert.False(t, status.UpdatePending)
+	assert.Equal(t, []string{"linux-image-6.8.0-41-generic", "linux-image-6.8.0-45-generic"}, status.Removable)
+
+	// Containers run the host kernel without any kernel package installed
+	handler.sysCalls = &mockSystemCalls{mockFiles: mockFiles{
+		"proc/sys/kernel/osrelease": {Data: []byte("6.1.0-28-amd64\n")},
+		"var/lib/dpkg/status":       {Data: []byte("Package: curl\nStatus: install ok installed\nVersion: 7.88.1-10\n")},
+	}}
+
+	db, err = handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	status, err = handler.kernelStatus(db, nil)
+	require.NoError(t, err)
+	assert.Empty(t, status.Latest)
+	assert.False(t, status.NewerInstalled)
+	assert.Empty(t, status.Installed)
+
+	// Empty lists are serialized as arrays rather than null
+	data, err := json.Marshal(status)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"installed":[]`)
+}
+
+// TestGetKernelStatus ensures the kernel item combines the snapshot with the dpkg database
+func TestGetKernelStatus(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{
+			"proc/sys/kernel/osrelease": {Data: []byte("6.8.0-51-generic\n")},
+			"var/lib/dpkg/status":       {Data: []byte(testKernelStatus)},
+		},
+		output: "linux-image-generic/noble-updates 6.8.0-52.53]\n",
+	}}
+
+	res, err := handler.GetKernelStatus(context.Background(), nil)
+	require.NoError(t, err)
+	assert.True(t, res.(*KernelStatus).UpdatePending)
+}
+
+// TestGetKernelStatusFailed ensures a kernel status that cannot be read is reported rather than left out
+func TestGetKernelStatusFailed(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{"var/lib/dpkg/status": {Data: []byte(testKernelStatus)}},
+		output:    "linux-image-generic/noble-updates 6.8.0-52.53]\n",
+	}}
+
+	_, err := handler.GetKernelStatus(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read running kernel release")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+
+	result := res.(*AllUpdatesResult)
+	assert.Nil(t, result.Kernel)
+	assert.Contains(t, result.SectionErrors, "kernel")
+}
@@ -0,0 +1,129 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"sort"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// sectionObsolete names the obsolete packages in AllUpdatesResult.SectionErrors
+const sectionObsolete = "obsolete_packages"
+
+// ObsoletePackage is an installed package of which no configured repository provides
+// the installed or a newer version, so it never receives updates
+type ObsoletePackage struct {
+	Name         string `json:"name"`
+	Architecture string `json:"architecture"`
+	Version      string `json:"version"` // Installed version
+	Source       string `json:"source"`
+}
+
+// ObsoleteResult lists the installed packages whose version no repository provides
+type ObsoleteResult struct {
+	Count   int               `json:"count"`
+	List    []string          `json:"list"`
+	Details []ObsoletePackage `json:"details"`
+}
+
+// GetObsoletePackages returns the installed versions only known from the dpkg status:
+// removed from the archive, installed from a local .deb or left over from a removed repository
+func (h *Handler) GetObsoletePackages(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.ObsoletePackagesCount == nil {
+		return nil, result.sectionError(sectionObsolete, "failed to determine obsolete packages")
+	}
+
+	return &ObsoleteResult{
+		Count:   *result.ObsoletePackagesCount,
+		List:    result.ObsoletePackagesList,
+		Details: result.ObsoletePackagesDetails,
+	}, nil
+}
+
+// obsoletePackages returns the installed packages of which neither the installed
+// version nor a newer one of the same architecture is in the repository indexes,
+// as apt list reports them "[installed,local]". A version from another repository,
+// e.g. the archive's php8.3 next to a PPA build, does not count. Architecture: all
+// versions match any architecture.
+func (h *Handler) obsoletePackages(ctx context.Context, db dpkgDatabase) ([]ObsoletePackage, error) {
+	names := make(map[string]bool, len(db))
+	for name, entries := range db {
+		for _, pkg := range entries {
+			if pkg.IsInstalled() {
+				names[name] = true
+			}
+		}
+	}
+
+	idx, err := h.loadPackageIndex(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(idx) == 0 && len(names) > 0 {
+		// Every package would be reported before the first apt update
+		return nil, errs.New("no installed package found in the APT package lists")
+	}
+
+	obsolete := []ObsoletePackage{}
+
+	for name, entries := range db {
+		for _, pkg := range entries {
+			if pkg.IsInstalled() && !idx.provides(pkg) {
+				obsolete = append(obsolete, ObsoletePackage{
+					Name:         name,
+					Architecture: pkg.Architecture,
+					Version:      pkg.Version,
+					Source:       pkg.Source,
+				})
+			}
+		}
+	}
+
+	sort.Slice(obsolete, func(i, j int) bool {
+		if obsolete[i].Name != obsolete[j].Name {
+			return obsolete[i].Name < obsolete[j].Name
+		}
+
+		return obsolete[i].Architecture < obsolete[j].Architecture
+	})
+
+	return obsolete, nil
+}
+
+// provides reports whether the index has the installed version of the package or, as
+// an update, a newer one for its architecture
+func (idx packageIndex) provides(pkg InstalledPackage) bool {
+	for _, entry := range idx.versions(pkg.Name, pkg.Version) {
+		if entry.Architecture == pkg.Architecture || entry.Architecture == "all" || pkg.Architecture == "all" {
+			return true
+		}
+	}
+
+	newest := idx.newest(pkg.Name, pkg.Architecture)
+
+	return newest != "" && compareVersions(newest, pkg.Version) > 0
+}
@@ -0,0 +1,132 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testObsoleteStatus = `Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.4
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2024a-2ubuntu1
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Version: 2.39-0ubuntu8.3
+
+Package: php8.3-cli
+Status: install ok installed
+Architecture: amd64
+Source: php8.3
+Version: 8.3.12-1+ubuntu24.04.1+deb.sury.org+1
+
+Package: mytool
+Status: install ok installed
+Architecture: amd64
+Version: 1.2.0
+
+Package: oldpkg
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0-1
+`
+
+	testObsoletePackages = `Package: openssl
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.5
+
+Package: tzdata
+Architecture: all
+Version: 2024b-0ubuntu0.24.04
+
+Package: libc6
+Architecture: amd64
+Version: 2.39-0ubuntu8.3
+
+Package: php8.3-cli
+Architecture: amd64
+Source: php8.3
+Version: 8.3.6-0ubuntu0.24.04.2
+`
+)
+
+// TestObsoletePackages ensures installed packages without any version in the repository indexes are reported
+func TestObsoletePackages(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status": {Data: []byte(testObsoleteStatus)},
+		"var/lib/apt/lists/archive.ubuntu.com_ubuntu_dists_noble-updates_InRelease": {
+			Data: []byte("Origin: Ubuntu\nSuite: noble-updates\n"),
+		},
+		"var/lib/apt/lists/archive.ubuntu.com_ubuntu_dists_noble-updates_main_binary-amd64_Packages": {
+			Data: []byte(testObsoletePackages),
+		},
+	}}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	obsolete, err := handler.obsoletePackages(context.Background(), db)
+	require.NoError(t, err)
+
+	// Older installed versions of indexed packages are updates, not obsolete packages, while the
+	// PPA build of php8.3-cli is obsolete although the archive has an older version of it
+	assert.Equal(t, []ObsoletePackage{
+		{Name: "libc6", Architecture: "i386", Version: "2.39-0ubuntu8.3", Source: "libc6"},
+		{Name: "mytool", Architecture: "amd64", Version: "1.2.0", Source: "mytool"},
+		{Name: "php8.3-cli", Architecture: "amd64", Version: "8.3.12-1+ubuntu24.04.1+deb.sury.org+1", Source: "php8.3"},
+	}, obsolete)
+}
+
+// TestObsoletePackagesWithoutLists ensures nothing is reported before the package lists were downloaded
+func TestObsoletePackagesWithoutLists(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status":    {Data: []byte(testObsoleteStatus)},
+		"var/lib/apt/lists/lock": {Data: []byte{}},
+	}}}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	_, err = handler.obsoletePackages(context.Background(), db)
+	assert.Error(t, err)
+
+	// The cause is returned by the item instead of reporting no obsolete packages
+	_, err = handler.GetObsoletePackages(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no installed package found in the APT package lists")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+
+	result := res.(*AllUpdatesResult)
+	assert.Nil(t, result.ObsoletePackagesCount)
+	assert.Contains(t, result.SectionErrors, "obsolete_packages")
+}
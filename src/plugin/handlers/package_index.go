@@ -0,0 +1,304 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+	"golang.zabbix.com/sdk/errs"
+)
+
+// aptListsDir is where apt stores the downloaded repository indexes
+const aptListsDir = "/var/lib/apt/lists"
+
+// packagesFileRe matches the binary package indexes in aptListsDir, plain or compressed
+var packagesFileRe = regexp.MustCompile(`_Packages(\.gz|\.xz|\.lz4)?$`)
+
+// releaseInfo holds the fields of a repository Release file
+type releaseInfo struct {
+	Origin   string
+	Label    string
+	Suite    string
+	Codename string
+	Site     string
+	Path     string
+}
+
+// indexedPackage is a package version available from a repository index
+type indexedPackage struct {
+	Name         string
+	Version      string
+	Architecture string
+	Origin       string
+	Label        string
+	Suite        string
+	Codename     string
+	Site         string
+	Path         string
+	Component    string
+	Section      string
+	Priority     string
+	// PhasedUpdatePercentage is the share of machines the version is rolled out to, 100 when not phased
+	PhasedUpdatePercentage int
+}
+
+// packageIndex maps package names to every version available from the configured repositories
+type packageIndex map[string][]indexedPackage
+
+// versions returns the index entries of the given package version, one per
+// repository it is available from
+func (idx packageIndex) versions(name, version string) []indexedPackage {
+	// Multiarch package names carry the architecture after a colon
+	name, _, _ = strings.Cut(name, ":")
+
+	var matches []indexedPackage
+	for _, pkg := range idx[name] {
+		if pkg.Version == version {
+			matches = append(matches, pkg)
+		}
+	}
+
+	return matches
+}
+
+// release returns the fields of the release the entry comes from
+func (p indexedPackage) release() releaseFields {
+	return releaseFields{
+		Origin:    p.Origin,
+		Label:     p.Label,
+		Archive:   p.Suite,
+		Codename:  p.Codename,
+		Component: p.Component,
+		Site:      p.Site,
+		Path:      p.Path,
+	}
+}
+
+// loadPackageIndex reads the Packages indexes and their Release files from
+// aptListsDir. When names is not nil, only the listed packages are kept.
+// Indexes that cannot be read are skipped and logged.
+func (h *Handler) loadPackageIndex(ctx context.Context, names map[string]bool) (packageIndex, error) {
+	entries, err := h.sysCalls.readDir(aptListsDir)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to list APT package lists")
+	}
+
+	// Release files are keyed by the file name prefix they share with their indexes,
+	// e.g. "archive.ubuntu.com_ubuntu_dists_noble-updates_"
+	releases := make(map[string]releaseInfo)
+	var packagesFiles []string
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+
+		switch {
+		case strings.HasSuffix(name, "_InRelease"):
+			release, err := h.readRelease(path.Join(aptListsDir, name))
+			if err != nil {
+				continue
+			}
+
+			release.Site, release.Path = listsFileRepository(name)
+			releases[strings.TrimSuffix(name, "InRelease")] = release
+		case strings.HasSuffix(name, "_Release"):
+			prefix := strings.TrimSuffix(name, "Release")
+			if _, ok := releases[prefix]; ok {
+				// InRelease already read
+				continue
+			}
+
+			release, err := h.readRelease(path.Join(aptListsDir, name))
+			if err != nil {
+				continue
+			}
+
+			release.Site, release.Path = listsFileRepository(name)
+			releases[prefix] = release
+		case packagesFileRe.MatchString(name):
+			packagesFiles = append(packagesFiles, name)
+		}
+	}
+
+	idx := make(packageIndex)
+
+	for _, name := range packagesFiles {
+		if ctx.Err() != nil {
+			return nil, errs.Wrap(ctx.Err(), "package index loading interrupted")
+		}
+
+		prefix := ""
+		for p := range releases {
+			if strings.HasPrefix(name, p) && len(p) > len(prefix) {
+				prefix = p
+			}
+		}
+
+		release := releases[prefix]
+		component := packagesComponent(strings.TrimPrefix(name, prefix))
+
+		err := h.readPackagesFile(path.Join(aptListsDir, name), func(stanza deb822Stanza) error {
+			pkgName := stanza["Package"]
+			if pkgName == "" || (names != nil && !names[pkgName]) {
+				return nil
+			}
+
+			phasedPercentage := 100
+			if v, err := strconv.Atoi(stanza["Phased-Update-Percentage"]); err == nil {
+				phasedPercentage = v
+			}
+
+			idx[pkgName] = append(idx[pkgName], indexedPackage{
+				Name:                   pkgName,
+				Version:                stanza["Version"],
+				Architecture:           stanza["Architecture"],
+				Origin:                 release.Origin,
+				Label:                  release.Label,
+				Suite:                  release.Suite,
+				Codename:               release.Codename,
+				Site:                   release.Site,
+				Path:                   release.Path,
+				Component:              component,
+				Section:                stanza["Section"],
+				Priority:               stanza["Priority"],
+				PhasedUpdatePercentage: phasedPercentage,
+			})
+
+			return nil
+		})
+		if err != nil {
+			// The other indexes still classify most packages
+			h.warnf("skipping package index %s: %s", name, err.Error())
+		}
+	}
+
+	return idx, nil
+}
+
+// listsFileRepository returns the host and URI path of the repository a file in
+// aptListsDir belongs to. The file names are the URIs with slashes stored as
+// underscores: "ppa.launchpadcontent.net_ondrej_php_ubuntu_dists_noble_InRelease".
+func listsFileRepository(name string) (string, string) {
+	site, rest, _ := strings.Cut(name, "_")
+	repoPath, _, _ := strings.Cut(rest, "_dists_")
+
+	return site, strings.ReplaceAll(repoPath, "_", "/")
+}
+
+// packagesComponent extracts the component from the part of an index file name
+// following its Release prefix, e.g. "universe_binary-amd64_Packages.lz4".
+// Flat repositories have no component.
+func packagesComponent(rest string) string {
+	component, _, ok := strings.Cut(rest, "_binary-")
+	if !ok {
+		return ""
+	}
+
+	// Slashes in the URI path are stored as underscores
+	return strings.ReplaceAll(component, "_", "/")
+}
+
+// readRelease parses the Release or clearsigned InRelease file at name
+func (h *Handler) readRelease(name string) (releaseInfo, error) {
+	data, err := h.readFile(name)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+
+	var release releaseInfo
+
+	err = parseDeb822(strings.NewReader(stripPGPSignature(string(data))), func(stanza deb822Stanza) error {
+		release = releaseInfo{
+			Origin:   stanza["Origin"],
+			Label:    stanza["Label"],
+			Suite:    stanza["Suite"],
+			Codename: stanza["Codename"],
+		}
+
+		// Only the first paragraph describes the release
+		return io.EOF
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return releaseInfo{}, errs.Wrapf(err, "failed to parse %s", name)
+	}
+
+	return release, nil
+}
+
+// readPackagesFile decompresses a Packages index according to its extension
+// and calls fn for every package stanza
+func (h *Handler) readPackagesFile(name string, fn func(deb822Stanza) error) error {
+	f, err := h.sysCalls.openFile(name)
+	if err != nil {
+		return errs.Wrap(err, "failed to open file")
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	var r io.Reader = f
+
+	switch path.Ext(name) {
+	case ".gz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return errs.Wrap(err, "failed to decompress gzip")
+		}
+		defer gz.Close() //nolint:errcheck // read-only stream
+
+		r = gz
+	case ".lz4":
+		r = lz4.NewReader(f)
+	case ".xz":
+		xzr, err := xz.NewReader(f)
+		if err != nil {
+			return errs.Wrap(err, "failed to decompress xz")
+		}
+
+		r = xzr
+	}
+
+	return parseDeb822(r, fn)
+}
+
+// readFile reads the whole file at name
+func (h *Handler) readFile(name string) ([]byte, error) {
+	f, err := h.sysCalls.openFile(name)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to open file")
+	}
+	defer f.Close() //nolint:errcheck // read-only file
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to read %s", name)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,215 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// testPackagesLZ4 is an LZ4 frame, as written by `lz4`, holding two package stanzas
+	// (openssl and openssh-server with Phased-Update-Percentage: 20)
+	testPackagesLZ4 = "04224d186440a7b1000000f1495061636b6167653a206f70656e73736c0a56657273696f6e3a20332e302e31" +
+		"332d307562756e7475332e350a4172636869746563747572653a20616d6436340a5072696f726974793a20696d70" +
+		"6f7274616e740a5365637443007b7574696c730a0a640086682d7365727665726b0092313a392e3670312d336c00" +
+		"1f316d000f866f7074696f6e616c6c00f0136e65740a5068617365642d5570646174652d50657263656e74616765" +
+		"3a2032300a0a000000004aea311c"
+
+	testInRelease = `-----BEGIN PGP SIGNED MESSAGE-----
+Hash: SHA512
+
+Origin: Ubuntu
+Label: Ubuntu
+Suite: noble-security
+Version: 24.04
+Codename: noble
+Components: main restricted universe multiverse
+Description: Ubuntu Noble 24.04
+-----BEGIN PGP SIGNATURE-----
+
+iQIzBAEBCgAdFiEEOl/M2HHMzJ0EKYD7hNf8ODyEtmwFAmY=
+-----END PGP SIGNATURE-----
+`
+
+	testRelease = `Origin: Docker
+Label: Docker CE
+Suite: noble
+Codename: noble
+Components: stable
+`
+
+	testUniversePackages = `Package: htop
+Architecture: amd64
+Version: 3.3.0-4build1
+Priority: optional
+Section: utils
+Description: interactive processes viewer
+ Htop is an ncursed-based process viewer similar to top, but it
+ allows one to scroll the list vertically and horizontally.
+ .
+ Tasks related to processes (killing, renicing) can be done without
+ entering their PIDs.
+
+Package: nmap
+Architecture: amd64
+Version: 7.94+git20230807.3be01efb1+dfsg-3build2
+Section: net
+`
+
+	// testRestrictedXZ is an xz stream, as written by `xz`, holding a linux-firmware stanza
+	testRestrictedXZ = "fd377a585a000004e6d6b4460200210116000000742fe5a3e0006400615d0028184866dbda3085fe16e914927c41" +
+		"d6ad9bac1317dfa04756a218d3592e0e7a2b592ea6dd72ff65a3ea9bcc8f58c74e6ddf46cdd262271943671a110dd56031" +
+		"b9145be1fa1fad4c114b5af4d2e055e7140a7933d0ad14b190d441f8842a0be00000000000af92d415c27493da00017d65" +
+		"4727cf871fb6f37d010000000004595a"
+
+	testDockerPackages = `Package: docker-ce
+Architecture: amd64
+Version: 5:27.3.1-1~ubuntu.24.04~noble
+Priority: optional
+Section: admin
+`
+)
+
+// TestLoadPackageIndex ensures plain, gzip and lz4 indexes are read together with their release fields
+func TestLoadPackageIndex(t *testing.T) {
+	lz4Data, err := hex.DecodeString(testPackagesLZ4)
+	require.NoError(t, err)
+
+	var gzData bytes.Buffer
+	gz := gzip.NewWriter(&gzData)
+	_, err = gz.Write([]byte(testUniversePackages))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	xzData, err := hex.DecodeString(testRestrictedXZ)
+	require.NoError(t, err)
+
+	lists := "var/lib/apt/lists/"
+	handler := &Handler{
+		sysCalls: &mockSystemCalls{mockFiles: mockFiles(fstest.MapFS{
+			lists + "archive.ubuntu.com_ubuntu_dists_noble-security_InRelease": {
+				Data: []byte(testInRelease),
+			},
+			lists + "archive.ubuntu.com_ubuntu_dists_noble-security_main_binary-amd64_Packages.lz4": {
+				Data: lz4Data,
+			},
+			lists + "archive.ubuntu.com_ubuntu_dists_noble-security_universe_binary-amd64_Packages.gz": {
+				Data: gzData.Bytes(),
+			},
+			lists + "archive.ubuntu.com_ubuntu_dists_noble-security_restricted_binary-amd64_Packages.xz": {
+				Data: xzData,
+			},
+			// Unreadable indexes are skipped
+			lists + "archive.ubuntu.com_ubuntu_dists_noble-security_multiverse_binary-amd64_Packages.gz": {
+				Data: []byte("not gzip"),
+			},
+			lists + "download.docker.com_linux_ubuntu_dists_noble_Release": {
+				Data: []byte(testRelease),
+			},
+			lists + "download.docker.com_linux_ubuntu_dists_noble_stable_binary-amd64_Packages": {
+				Data: []byte(testDockerPackages),
+			},
+			lists + "lock":    {Data: []byte{}},
+			lists + "partial": {Mode: fs.ModeDir},
+		})},
+	}
+
+	idx, err := handler.loadPackageIndex(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, idx, 6)
+
+	firmware := idx.versions("linux-firmware", "20240318.git3b128b60-0ubuntu2.5")
+	require.Len(t, firmware, 1)
+	assert.Equal(t, "restricted", firmware[0].Component)
+	assert.Equal(t, "kernel", firmware[0].Section)
+
+	assert.Equal(t, []indexedPackage{{
+		Name:                   "openssh-server",
+		Version:                "1:9.6p1-3ubuntu13.5",
+		Architecture:           "amd64",
+		Origin:                 "Ubuntu",
+		Label:                  "Ubuntu",
+		Suite:                  "noble-security",
+		Codename:               "noble",
+		Site:                   "archive.ubuntu.com",
+		Path:                   "ubuntu",
+		Component:              "main",
+		Section:                "net",
+		Priority:               "optional",
+		PhasedUpdatePercentage: 20,
+	}}, idx["openssh-server"])
+
+	htop := idx.versions("htop", "3.3.0-4build1")
+	require.Len(t, htop, 1)
+	assert.Equal(t, "universe", htop[0].Component)
+	assert.Equal(t, 100, htop[0].PhasedUpdatePercentage)
+
+	docker := idx.versions("docker-ce:amd64", "5:27.3.1-1~ubuntu.24.04~noble")
+	require.Len(t, docker, 1)
+	assert.Equal(t, "Docker", docker[0].Origin)
+	assert.Equal(t, "stable", docker[0].Component)
+
+	categories, rule := classifyReleases(indexedReleases(idx.versions("openssl", "3.0.13-0ubuntu3.5")))
+	assert.True(t, categories[UpdateTypeSecurity])
+	assert.Equal(t, "ubuntu-security", rule)
+
+	categories, _ = classifyReleases(indexedReleases(htop))
+	assert.True(t, categories[UpdateTypeOptional])
+
+	categories, rule = classifyReleases(indexedReleases(docker))
+	assert.False(t, categories[UpdateTypeSecurity])
+	assert.Empty(t, rule)
+
+	// Only requested packages are kept
+	idx, err = handler.loadPackageIndex(context.Background(), map[string]bool{"nmap": true})
+	require.NoError(t, err)
+	assert.Len(t, idx, 1)
+	assert.Len(t, idx["nmap"], 1)
+}
+
+// TestReadPackagesFileCorrupt ensures truncated or mislabelled compressed indexes fail to read
+func TestReadPackagesFileCorrupt(t *testing.T) {
+	lz4Data, err := hex.DecodeString(testPackagesLZ4)
+	require.NoError(t, err)
+
+	xzData, err := hex.DecodeString(testRestrictedXZ)
+	require.NoError(t, err)
+
+	handler := &Handler{
+		sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+			"truncated_Packages.lz4": {Data: lz4Data[:len(lz4Data)-10]},
+			"truncated_Packages.xz":  {Data: xzData[:len(xzData)-4]},
+			"plain_Packages.xz":      {Data: []byte(testDockerPackages)},
+		}},
+	}
+
+	for _, name := range []string{"/truncated_Packages.lz4", "/truncated_Packages.xz", "/plain_Packages.xz"} {
+		err := handler.readPackagesFile(name, func(deb822Stanza) error { return nil })
+		assert.Error(t, err, name)
+	}
+}
@@ -20,6 +20,7 @@ package handlers
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,15 +28,26 @@ import (
 
 // TestPhasedUpdatesHandling ensures that phased updates are counted separately
 func TestPhasedUpdatesHandling(t *testing.T) {
-	// Create a test handler with real system calls (will use actual apt-get)
 	handler := &Handler{
-		sysCalls: osWrapper{},
+		sysCalls: &phasedSystemCalls{},
 	}
 
 	result, err := handler.checkAPTUpdates(context.Background(), UpdateTypeAll, false)
 	assert.NoError(t, err)
-	// Should return some updates (number depends on actual system state)
-	assert.GreaterOrEqual(t, result.AvailableUpdates, 0)
+	// Packages deferred due to phasing are not upgraded
+	assert.Equal(t, 2, result.AvailableUpdates)
+
+	result, err = handler.collectUpdates(context.Background(), UpdateTypeAll, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.AvailableUpdates)
+
+	phased := []string{}
+	for _, pkg := range result.PackageDetailsList {
+		if pkg.IsPhased {
+			phased = append(phased, pkg.Name)
+		}
+	}
+	assert.Equal(t, []string{"libgnutls30t64", "mesa-vulkan-drivers"}, phased)
 }
 
 // TestParsePackageLine ensures correct parsing of apt list output (for old method)
@@ -82,9 +94,8 @@ func TestParsePackageLine(t *testing.T) {
 
 // TestGetAllUpdatesWithPhased excludes phased from regular counts but includes them in total
 func TestGetAllUpdatesWithPhased(t *testing.T) {
-	// Create a test handler with real system calls (will use actual apt-get)
 	handler := &Handler{
-		sysCalls: osWrapper{},
+		sysCalls: &phasedSystemCalls{},
 	}
 
 	result, err := handler.GetAllUpdates(context.Background(), nil)
@@ -94,39 +105,23 @@ func TestGetAllUpdatesWithPhased(t *testing.T) {
 	resultObj, ok := result.(*AllUpdatesResult)
 	assert.True(t, ok, "GetAllUpdates should return *AllUpdatesResult")
 
-	// Should have some updates or zero if system is up-to-date
-	assert.GreaterOrEqual(t, resultObj.AllUpdatesCount, 0)
-
-	// Verify the structure contains all expected fields for phased updates
-	assert.NotNil(t, resultObj.PhasedUpdatesList)
-	assert.NotNil(t, resultObj.PhasedUpdatesDetails)
-
-	// The actual count depends on whether the system has phased updates available
-	// On some systems there may be 0 phased updates, which is valid behavior
-	phasedCount := resultObj.PhasedUpdatesCount
-	totalCount := resultObj.AllUpdatesCount
-
-	if phasedCount > 0 {
-		// Verify that if there are phased updates, they have the IsPhased field set correctly
-		for _, pkg := range resultObj.PhasedUpdatesDetails {
-			assert.Equal(t, true, pkg.IsPhased, "phased updates should have IsPhased=true")
-		}
+	assert.Equal(t, 4, resultObj.AllUpdatesCount)
+	assert.Equal(t, 2, resultObj.PhasedUpdatesCount)
+	assert.Equal(t, []string{"libgnutls30t64", "mesa-vulkan-drivers"}, resultObj.PhasedUpdatesList)
+	for _, pkg := range resultObj.PhasedUpdatesDetails {
+		assert.True(t, pkg.IsPhased, "phased updates should have IsPhased=true")
 	}
 
-	// Verify recommended count excludes phased updates
-	// This is the key test - recommended should not include phased packages
-	recommendedCount := resultObj.RecommendedUpdatesCount
-
-	// Recommended + Phased should equal total (or be close if there are security/optional overlaps)
-	assert.LessOrEqual(t, phasedCount+recommendedCount, totalCount,
+	// Recommended updates exclude the phased packages
+	assert.Equal(t, []string{"vim"}, resultObj.RecommendedUpdatesList)
+	assert.LessOrEqual(t, resultObj.PhasedUpdatesCount+resultObj.RecommendedUpdatesCount, resultObj.AllUpdatesCount,
 		"phased count + recommended count should not exceed total updates")
 }
 
 // TestGetAllUpdatesPhasedInSecurity ensures phased updates are excluded from security counts too
 func TestGetAllUpdatesPhasedInSecurity(t *testing.T) {
-	// Create a test handler with real system calls (will use actual apt-get)
 	handler := &Handler{
-		sysCalls: osWrapper{},
+		sysCalls: &phasedSystemCalls{},
 	}
 
 	result, err := handler.GetAllUpdates(context.Background(), nil)
@@ -136,47 +131,102 @@ func TestGetAllUpdatesPhasedInSecurity(t *testing.T) {
 	resultObj, ok := result.(*AllUpdatesResult)
 	assert.True(t, ok, "GetAllUpdates should return *AllUpdatesResult")
 
-	// Should have some updates or zero if system is up-to-date
-	assert.GreaterOrEqual(t, resultObj.AllUpdatesCount, 0)
-
-	// Verify the structure contains all expected fields for phased updates
-	assert.NotNil(t, resultObj.PhasedUpdatesList)
-	assert.NotNil(t, resultObj.PhasedUpdatesDetails)
-	assert.NotNil(t, resultObj.SecurityUpdatesList)
-	assert.NotNil(t, resultObj.SecurityUpdatesDetails)
+	// libgnutls30t64 comes from the security pocket but is phased
+	assert.Equal(t, 1, resultObj.SecurityUpdatesCount)
+	assert.Equal(t, []string{"openssl"}, resultObj.SecurityUpdatesList)
+	assert.Contains(t, resultObj.PhasedUpdatesList, "libgnutls30t64")
+}
 
-	// The actual count depends on whether the system has phased or security updates available
-	phasedCount := resultObj.PhasedUpdatesCount
+// testPhasedUpgradeOutput is apt-get -s upgrade output with phased updates deferred
+const testPhasedUpgradeOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+Calculating upgrade...
+The following upgrades have been deferred due to phasing:
+  libgnutls30t64 mesa-vulkan-drivers
+The following packages will be upgraded:
+  openssl vim
+2 upgraded, 0 newly installed, 0 to remove and 2 not upgraded.
+Inst openssl [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-security [amd64])
+Inst vim [2:9.1.0016-1ubuntu7.2] (2:9.1.0016-1ubuntu7.3 Ubuntu:24.04/noble-updates [amd64])
+Conf openssl (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-security [amd64])
+Conf vim (2:9.1.0016-1ubuntu7.3 Ubuntu:24.04/noble-updates [amd64])
+`
+
+// testPhasedIncludedOutput is apt-get -s upgrade output with phased updates included
+const testPhasedIncludedOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+Calculating upgrade...
+The following packages will be upgraded:
+  libgnutls30t64 mesa-vulkan-drivers openssl vim
+4 upgraded, 0 newly installed, 0 to remove and 0 not upgraded.
+Inst libgnutls30t64 [3.8.3-1.1ubuntu3.2] (3.8.3-1.1ubuntu3.3 Ubuntu:24.04/noble-security [amd64])
+Inst mesa-vulkan-drivers [24.0.9-0ubuntu0.1] (24.2.8-1ubuntu1~24.04.1 Ubuntu:24.04/noble-updates [amd64])
+Inst openssl [3.0.13-0ubuntu3.4] (3.0.13-0ubuntu3.5 Ubuntu:24.04/noble-security [amd64])
+Inst vim [2:9.1.0016-1ubuntu7.2] (2:9.1.0016-1ubuntu7.3 Ubuntu:24.04/noble-updates [amd64])
+`
+
+// testPhasedPolicyOutput is apt-cache policy output for the phased test updates
+const testPhasedPolicyOutput = `libgnutls30t64:
+  Installed: 3.8.3-1.1ubuntu3.2
+  Candidate: 3.8.3-1.1ubuntu3.3
+  Version table:
+     3.8.3-1.1ubuntu3.3 500
+        500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+ *** 3.8.3-1.1ubuntu3.2 100
+        100 /var/lib/dpkg/status
+mesa-vulkan-drivers:
+  Installed: 24.0.9-0ubuntu0.1
+  Candidate: 24.2.8-1ubuntu1~24.04.1
+  Version table:
+     24.2.8-1ubuntu1~24.04.1 500
+        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+ *** 24.0.9-0ubuntu0.1 100
+        100 /var/lib/dpkg/status
+openssl:
+  Installed: 3.0.13-0ubuntu3.4
+  Candidate: 3.0.13-0ubuntu3.5
+  Version table:
+     3.0.13-0ubuntu3.5 500
+        500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+ *** 3.0.13-0ubuntu3.4 100
+        100 /var/lib/dpkg/status
+vim:
+  Installed: 2:9.1.0016-1ubuntu7.2
+  Candidate: 2:9.1.0016-1ubuntu7.3
+  Version table:
+     2:9.1.0016-1ubuntu7.3 500
+        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+ *** 2:9.1.0016-1ubuntu7.2 100
+        100 /var/lib/dpkg/status
+`
+
+// phasedSystemCalls returns apt-get -s upgrade output depending on whether
+// phased updates are included
+type phasedSystemCalls struct {
+	mockFiles
+}
 
-	if phasedCount > 0 {
-		// Verify that if there are phased updates, they have the IsPhased field set correctly
-		for _, pkg := range resultObj.PhasedUpdatesDetails {
-			assert.Equal(t, true, pkg.IsPhased, "phased updates should have IsPhased=true")
-		}
+func (p *phasedSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	command := strings.Join(args, " ")
+	if command == "LC_ALL=C LANG=C apt-cache policy" {
+		return []byte(testSourcesPolicyOutput), nil
 	}
-}
 
-// mockPhasedSystemCalls is a mock that returns the exact output provided
-// and handles both apt list and apt-get -s upgrade commands
-type mockPhasedSystemCalls struct {
-	output string
-}
+	if strings.HasPrefix(command, "LC_ALL=C LANG=C apt-cache policy ") {
+		return []byte(testPhasedPolicyOutput), nil
+	}
 
-func (m *mockPhasedSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
-	// For apt-cache policy commands (used in isPackageOfType)
-	if name == "apt-cache" && len(args) > 0 && args[0] == "policy" {
-		// Return mock output that indicates main repository (not security/universe)
-		return []byte("Package: " + args[1] + "\n" +
-			"Candidate: 1.0\n" +
-			"Version table:\n" +
-			"   1.0 500\n"),
-		nil
+	if args[len(args)-1] != "upgrade" {
+		return []byte{}, nil
 	}
-	return []byte{}, nil
-}
 
-func newMockPhasedSystemCalls(output string) systemCalls {
-	return &mockPhasedSystemCalls{
-		output: output,
+	for _, arg := range args {
+		if arg == "APT::Get::Always-Include-Phased-Updates=true" {
+			return []byte(testPhasedIncludedOutput), nil
+		}
 	}
+
+	return []byte(testPhasedUpgradeOutput), nil
 }
@@ -0,0 +1,299 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"sort"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// Pin priorities outside 0..1000 change which versions apt installs, see apt_preferences(5)
+const (
+	pinNeverInstall = 0    // Versions below never get installed
+	pinForceInstall = 1000 // Versions above are installed even if this means a downgrade
+)
+
+// DowngradedPackage is an installed package newer than every version in the repositories
+type DowngradedPackage struct {
+	Name          string `json:"name"`
+	Architecture  string `json:"architecture"`
+	Installed     string `json:"installed_version"`
+	Candidate     string `json:"candidate_version,omitempty"`
+	Newest        string `json:"newest_available_version"` // Newest version in the repositories
+	WillDowngrade bool   `json:"will_downgrade"`           // A pin above 1000 makes the older candidate win
+}
+
+// PinnedSecurityUpdate is a security update kept back by a pin priority below 0 or above 1000
+type PinnedSecurityUpdate struct {
+	Name         string `json:"name"`
+	Architecture string `json:"architecture"`
+	Installed    string `json:"installed_version"`
+	Candidate    string `json:"candidate_version,omitempty"`
+	Blocked      string `json:"blocked_version"` // Newest security update apt will not install
+	Priority     int    `json:"pin_priority"`    // The offending pin priority
+	SecurityRule string `json:"security_rule,omitempty"`
+}
+
+// PinAnomalies are the packages the APT preferences keep from the repository versions
+type PinAnomalies struct {
+	Count               int                    `json:"count"`
+	DowngradesCount     int                    `json:"downgrades_count"`
+	PinnedSecurityCount int                    `json:"pinned_security_count"`
+	Downgrades          []DowngradedPackage    `json:"downgrades"`
+	PinnedSecurity      []PinnedSecurityUpdate `json:"pinned_security"`
+}
+
+// GetPinAnomalies returns the packages installed in a newer version than the repositories
+// provide and the security updates held back by pin priorities
+func (h *Handler) GetPinAnomalies(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.PinAnomalies == nil {
+		return nil, errs.New("failed to check APT pin priorities")
+	}
+
+	return result.PinAnomalies, nil
+}
+
+// pinAnomalies compares the installed packages with their apt-cache policy version
+// tables. Only packages whose newest indexed version is neither the installed one
+// nor a pending update target are looked up, in a single batched call.
+func (h *Handler) pinAnomalies(ctx context.Context, db dpkgDatabase, updates []UpdateInfo) (*PinAnomalies, error) {
+	names := make(map[string]bool, len(db))
+	for name, entries := range db {
+		for _, pkg := range entries {
+			if pkg.IsInstalled() {
+				names[name] = true
+			}
+		}
+	}
+
+	idx, err := h.loadPackageIndex(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string, len(updates))
+	for _, update := range updates {
+		targets[update.Name] = update.Target
+	}
+
+	var (
+		candidates []InstalledPackage
+		queries    []string
+	)
+
+	for name, entries := range db {
+		for _, pkg := range entries {
+			if !pkg.IsInstalled() {
+				continue
+			}
+
+			newest := idx.newest(name, pkg.Architecture)
+			if newest == "" || newest == pkg.Version || newest == targets[name] || newest == targets[name+":"+pkg.Architecture] {
+				continue
+			}
+
+			candidates = append(candidates, pkg)
+			queries = append(queries, policyName(pkg))
+		}
+	}
+
+	anomalies := &PinAnomalies{Downgrades: []DowngradedPackage{}, PinnedSecurity: []PinnedSecurityUpdate{}}
+	if len(candidates) == 0 {
+		return anomalies, nil
+	}
+
+	policies, err := h.aptCachePolicy(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceReleases, err := h.aptSourceReleases(ctx)
+	if err != nil {
+		// Sources are classified by suite and host only
+		sourceReleases = nil
+	}
+
+	for i, policy := range installedPolicies(policies, candidates) {
+		pkg := candidates[i]
+		if policy == nil {
+			continue
+		}
+
+		if downgrade, ok := policy.downgrade(pkg); ok {
+			anomalies.Downgrades = append(anomalies.Downgrades, downgrade)
+
+			continue
+		}
+
+		if pinned, ok := h.pinnedSecurityUpdate(pkg, policy, idx, sourceReleases); ok {
+			anomalies.PinnedSecurity = append(anomalies.PinnedSecurity, pinned)
+		}
+	}
+
+	sort.Slice(anomalies.Downgrades, func(i, j int) bool {
+		return anomalies.Downgrades[i].Name < anomalies.Downgrades[j].Name
+	})
+	sort.Slice(anomalies.PinnedSecurity, func(i, j int) bool {
+		return anomalies.PinnedSecurity[i].Name < anomalies.PinnedSecurity[j].Name
+	})
+
+	anomalies.DowngradesCount = len(anomalies.Downgrades)
+	anomalies.PinnedSecurityCount = len(anomalies.PinnedSecurity)
+	anomalies.Count = anomalies.DowngradesCount + anomalies.PinnedSecurityCount
+
+	return anomalies, nil
+}
+
+// policyName qualifies the package name with its architecture for apt-cache policy
+func policyName(pkg InstalledPackage) string {
+	if pkg.Architecture == "" || pkg.Architecture == "all" {
+		return pkg.Name
+	}
+
+	return pkg.Name + ":" + pkg.Architecture
+}
+
+// newest returns the newest indexed version of the package for the given architecture
+func (idx packageIndex) newest(name, arch string) string {
+	newest := ""
+	for _, pkg := range idx[name] {
+		if pkg.Architecture != arch && pkg.Architecture != "all" && arch != "all" {
+			continue
+		}
+
+		if newest == "" || compareVersions(pkg.Version, newest) > 0 {
+			newest = pkg.Version
+		}
+	}
+
+	return newest
+}
+
+// downgrade reports an installed version newer than every version the repositories provide
+func (p *packagePolicy) downgrade(pkg InstalledPackage) (DowngradedPackage, bool) {
+	newest := ""
+	for _, v := range p.repositoryVersions() {
+		if newest == "" || compareVersions(v.Version, newest) > 0 {
+			newest = v.Version
+		}
+	}
+
+	if newest == "" || compareVersions(pkg.Version, newest) <= 0 {
+		return DowngradedPackage{}, false
+	}
+
+	return DowngradedPackage{
+		Name:          pkg.Name,
+		Architecture:  pkg.Architecture,
+		Installed:     pkg.Version,
+		Candidate:     p.Candidate,
+		Newest:        newest,
+		WillDowngrade: p.Candidate != "" && compareVersions(p.Candidate, pkg.Version) < 0,
+	}, true
+}
+
+// pinnedSecurityUpdate returns the newest security update newer than both the
+// installed version and the candidate that is kept back by its own pin below 0
+// or by a pin above 1000 on the candidate
+func (h *Handler) pinnedSecurityUpdate(
+	pkg InstalledPackage, policy *packagePolicy, idx packageIndex, sourceReleases map[string]releaseFields,
+) (PinnedSecurityUpdate, bool) {
+	var (
+		pinned PinnedSecurityUpdate
+		found  bool
+	)
+
+	candidatePriority := 0
+	if candidate, ok := policy.version(policy.Candidate); ok {
+		candidatePriority = candidate.Priority
+	}
+
+	for _, v := range policy.repositoryVersions() {
+		if compareVersions(v.Version, pkg.Version) <= 0 ||
+			(policy.Candidate != "" && compareVersions(v.Version, policy.Candidate) <= 0) {
+			continue
+		}
+
+		priority := v.Priority
+		if priority >= pinNeverInstall {
+			priority = candidatePriority
+		}
+
+		if priority >= pinNeverInstall && priority <= pinForceInstall {
+			// Kept back by an ordinary priority, e.g. backports
+			continue
+		}
+
+		if found && compareVersions(v.Version, pinned.Blocked) <= 0 {
+			continue
+		}
+
+		section := ""
+		if entries := idx.versions(pkg.Name, v.Version); len(entries) > 0 {
+			section = entries[0].Section
+		}
+
+		releases := policy.targetReleases(v.Version, sourceReleases)
+		update := UpdateInfo{Name: pkg.Name, Current: pkg.Version, Target: v.Version}
+		categories, rule := classifyReleases(releases)
+		update.SecurityRule = rule
+		h.applyRules(&update, categories, section, releases)
+
+		if !categories[UpdateTypeSecurity] {
+			continue
+		}
+
+		pinned = PinnedSecurityUpdate{
+			Name:         pkg.Name,
+			Architecture: pkg.Architecture,
+			Installed:    pkg.Version,
+			Candidate:    policy.Candidate,
+			Blocked:      v.Version,
+			Priority:     priority,
+			SecurityRule: update.SecurityRule,
+		}
+		found = true
+	}
+
+	return pinned, found
+}
+
+// repositoryVersions returns the entries of the version table available from a repository
+func (p *packagePolicy) repositoryVersions() []policyVersion {
+	var versions []policyVersion
+
+	for _, v := range p.Versions {
+		for _, source := range v.Sources {
+			if source.Suite != "" {
+				versions = append(versions, v)
+
+				break
+			}
+		}
+	}
+
+	return versions
+}
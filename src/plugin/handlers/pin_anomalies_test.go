@@ -0,0 +1,194 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPinStatus = `Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.4
+
+Package: curl
+Status: install ok installed
+Architecture: amd64
+Version: 8.5.0-2ubuntu10.4
+
+Package: php8.3-cli
+Status: install ok installed
+Architecture: amd64
+Version: 8.3.12-1+ubuntu24.04.1+deb.sury.org+1
+
+Package: zabbix-agent2
+Status: install ok installed
+Architecture: amd64
+Version: 1:7.0.5-1+ubuntu24.04
+
+Package: htop
+Status: install ok installed
+Architecture: amd64
+Version: 3.3.0-4
+
+Package: vim
+Status: install ok installed
+Architecture: amd64
+Version: 2:9.1.0016-1ubuntu7
+`
+
+	testPinPackages = `Package: openssl
+Architecture: amd64
+Version: 3.0.13-0ubuntu3.5
+
+Package: curl
+Architecture: amd64
+Version: 8.5.0-2ubuntu10.5
+
+Package: php8.3-cli
+Architecture: amd64
+Version: 8.3.6-0ubuntu0.24.04.2
+
+Package: zabbix-agent2
+Architecture: amd64
+Version: 1:7.0.4-1+ubuntu24.04
+
+Package: htop
+Architecture: amd64
+Version: 3.3.0-4build1
+
+Package: vim
+Architecture: amd64
+Version: 2:9.1.0016-1ubuntu8
+`
+
+	testPinPolicyOutput = `openssl:
+  Installed: 3.0.13-0ubuntu3.4
+  Candidate: 3.0.13-0ubuntu3.4
+  Version table:
+     3.0.13-0ubuntu3.5 500
+        500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+ *** 3.0.13-0ubuntu3.4 1001
+        100 /var/lib/dpkg/status
+curl:
+  Installed: 8.5.0-2ubuntu10.4
+  Candidate: 8.5.0-2ubuntu10.4
+  Version table:
+     8.5.0-2ubuntu10.5 -1
+        500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+ *** 8.5.0-2ubuntu10.4 100
+        100 /var/lib/dpkg/status
+php8.3-cli:
+  Installed: 8.3.12-1+ubuntu24.04.1+deb.sury.org+1
+  Candidate: 8.3.12-1+ubuntu24.04.1+deb.sury.org+1
+  Version table:
+ *** 8.3.12-1+ubuntu24.04.1+deb.sury.org+1 100
+        100 /var/lib/dpkg/status
+     8.3.6-0ubuntu0.24.04.2 500
+        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+zabbix-agent2:
+  Installed: 1:7.0.5-1+ubuntu24.04
+  Candidate: 1:7.0.4-1+ubuntu24.04
+  Version table:
+ *** 1:7.0.5-1+ubuntu24.04 100
+        100 /var/lib/dpkg/status
+     1:7.0.4-1+ubuntu24.04 1001
+        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+vim:
+  Installed: 2:9.1.0016-1ubuntu7
+  Candidate: 2:9.1.0016-1ubuntu7
+  Version table:
+     2:9.1.0016-1ubuntu8 100
+        100 http://archive.ubuntu.com/ubuntu noble-backports/main amd64 Packages
+ *** 2:9.1.0016-1ubuntu7 100
+        100 /var/lib/dpkg/status
+`
+)
+
+// pinSystemCalls returns testPinPolicyOutput for apt-cache policy with packages,
+// recording the queried packages, and the package files without
+type pinSystemCalls struct {
+	mockFiles
+	queried []string
+}
+
+func (p *pinSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if strings.Join(args, " ") == "LC_ALL=C LANG=C apt-cache policy" {
+		return []byte(testSourcesPolicyOutput), nil
+	}
+
+	p.queried = append(p.queried, args[4:]...)
+
+	return []byte(testPinPolicyOutput), nil
+}
+
+// TestPinAnomalies ensures downgrades and security updates kept back by pins are reported
+func TestPinAnomalies(t *testing.T) {
+	sysCalls := &pinSystemCalls{mockFiles: mockFiles{
+		"var/lib/dpkg/status": {Data: []byte(testPinStatus)},
+		"var/lib/apt/lists/archive.ubuntu.com_ubuntu_dists_noble-updates_main_binary-amd64_Packages": {
+			Data: []byte(testPinPackages),
+		},
+	}}
+	handler := &Handler{sysCalls: sysCalls}
+
+	db, err := handler.readDpkgStatus()
+	require.NoError(t, err)
+
+	anomalies, err := handler.pinAnomalies(context.Background(), db, []UpdateInfo{{Name: "htop", Target: "3.3.0-4build1"}})
+	require.NoError(t, err)
+
+	// Pending updates are not looked up
+	assert.NotContains(t, sysCalls.queried, "htop:amd64")
+
+	assert.Equal(t, []DowngradedPackage{
+		{
+			Name: "php8.3-cli", Architecture: "amd64",
+			Installed: "8.3.12-1+ubuntu24.04.1+deb.sury.org+1", Candidate: "8.3.12-1+ubuntu24.04.1+deb.sury.org+1",
+			Newest: "8.3.6-0ubuntu0.24.04.2",
+		},
+		{
+			Name: "zabbix-agent2", Architecture: "amd64",
+			Installed: "1:7.0.5-1+ubuntu24.04", Candidate: "1:7.0.4-1+ubuntu24.04",
+			Newest: "1:7.0.4-1+ubuntu24.04", WillDowngrade: true,
+		},
+	}, anomalies.Downgrades)
+
+	// vim is kept back by the ordinary backports priority
+	assert.Equal(t, []PinnedSecurityUpdate{
+		{
+			Name: "curl", Architecture: "amd64", Installed: "8.5.0-2ubuntu10.4", Candidate: "8.5.0-2ubuntu10.4",
+			Blocked: "8.5.0-2ubuntu10.5", Priority: -1, SecurityRule: "ubuntu-security",
+		},
+		{
+			Name: "openssl", Architecture: "amd64", Installed: "3.0.13-0ubuntu3.4", Candidate: "3.0.13-0ubuntu3.4",
+			Blocked: "3.0.13-0ubuntu3.5", Priority: 1001, SecurityRule: "ubuntu-security",
+		},
+	}, anomalies.PinnedSecurity)
+
+	assert.Equal(t, 4, anomalies.Count)
+	assert.Equal(t, 2, anomalies.DowngradesCount)
+	assert.Equal(t, 2, anomalies.PinnedSecurityCount)
+}
@@ -0,0 +1,373 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// policyBatchSize bounds the number of packages passed to a single apt-cache
+// policy call to stay well below the argument length limit
+const policyBatchSize = 500
+
+// packagePolicy is the apt-cache policy of a single package
+type packagePolicy struct {
+	Installed string
+	Candidate string
+	Versions  []policyVersion
+}
+
+// policyVersion is an entry of a package's version table
+type policyVersion struct {
+	Version   string
+	Priority  int // Pin priority of the version
+	Installed bool
+	Sources   []policySource
+}
+
+// policySource is a repository (or the dpkg status file) a version is available from
+type policySource struct {
+	Priority     int
+	URI          string
+	Suite        string
+	Component    string
+	Architecture string
+}
+
+// aptCachePolicy runs apt-cache policy for all given packages at once and
+// returns the parsed policies keyed by package name
+func (h *Handler) aptCachePolicy(ctx context.Context, names []string) (map[string]*packagePolicy, error) {
+	policies := make(map[string]*packagePolicy, len(names))
+
+	for start := 0; start < len(names); start += policyBatchSize {
+		end := min(start+policyBatchSize, len(names))
+
+		// Force C locale so the field names are stable
+		args := append([]string{"LC_ALL=C", "LANG=C", "apt-cache", "policy"}, names[start:end]...)
+
+		output, err := h.sysCalls.execCommand(ctx, "env", args...)
+		if err != nil {
+			return nil, errs.Wrap(err, "failed to execute apt-cache policy")
+		}
+
+		for name, policy := range parsePolicy(string(output)) {
+			policies[name] = policy
+		}
+	}
+
+	return policies, nil
+}
+
+// installedPolicies returns the policies of the installed packages queried by policyName, in
+// the order of pkgs, nil for packages apt-cache printed nothing for. apt-cache omits the native
+// architecture from the package header, so the policy printed under the bare name is only
+// taken by a package when no other architecture of it is missing its qualified header.
+func installedPolicies(policies map[string]*packagePolicy, pkgs []InstalledPackage) []*packagePolicy {
+	unqualified := make(map[string]int)
+	for _, pkg := range pkgs {
+		if _, ok := policies[policyName(pkg)]; !ok {
+			unqualified[pkg.Name]++
+		}
+	}
+
+	matched := make([]*packagePolicy, len(pkgs))
+	for i, pkg := range pkgs {
+		if policy, ok := policies[policyName(pkg)]; ok {
+			matched[i] = policy
+		} else if unqualified[pkg.Name] == 1 {
+			matched[i] = policies[pkg.Name]
+		}
+	}
+
+	return matched
+}
+
+// parsePolicy parses the output of apt-cache policy for one or more packages:
+//
+//	openssl:
+//	  Installed: 3.0.13-0ubuntu3.4
+//	  Candidate: 3.0.13-0ubuntu3.5
+//	  Version table:
+//	     3.0.13-0ubuntu3.5 500
+//	        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+//	 *** 3.0.13-0ubuntu3.4 100
+//	        100 /var/lib/dpkg/status
+func parsePolicy(output string) map[string]*packagePolicy {
+	policies := make(map[string]*packagePolicy)
+
+	var (
+		policy  *packagePolicy
+		version *policyVersion
+	)
+
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		line := sc.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			continue
+		}
+
+		// Package header, not indented: "openssl:" or "libc6:i386:"
+		if line[0] != ' ' {
+			if !strings.HasSuffix(line, ":") || strings.HasPrefix(line, "N: ") || strings.HasPrefix(line, "W: ") {
+				policy = nil
+
+				continue
+			}
+
+			policy = &packagePolicy{}
+			version = nil
+			policies[strings.TrimSuffix(line, ":")] = policy
+
+			continue
+		}
+
+		if policy == nil {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "Installed:"):
+			policy.Installed = policyValue(trimmed)
+		case strings.HasPrefix(trimmed, "Candidate:"):
+			policy.Candidate = policyValue(trimmed)
+		case trimmed == "Version table:":
+		case strings.HasPrefix(trimmed, "***") || indentation(line) < 8:
+			// Version line: "     <version> <priority>", marked with *** when installed
+			fields := strings.Fields(strings.TrimPrefix(trimmed, "***"))
+			if len(fields) < 2 {
+				continue
+			}
+
+			priority, _ := strconv.Atoi(fields[1])
+			policy.Versions = append(policy.Versions, policyVersion{
+				Version:   fields[0],
+				Priority:  priority,
+				Installed: strings.HasPrefix(trimmed, "***"),
+			})
+			version = &policy.Versions[len(policy.Versions)-1]
+		case version != nil:
+			// Source line: "<priority> <uri> <suite>/<component> <arch> Packages"
+			// or "<priority> /var/lib/dpkg/status"
+			version.Sources = append(version.Sources, parsePolicySource(strings.Fields(trimmed)))
+		}
+	}
+
+	return policies
+}
+
+// parsePolicySource converts the fields of a version table source line
+func parsePolicySource(fields []string) policySource {
+	var source policySource
+
+	if len(fields) > 0 {
+		source.Priority, _ = strconv.Atoi(fields[0])
+	}
+
+	if len(fields) > 1 {
+		source.URI = fields[1]
+	}
+
+	if len(fields) > 2 {
+		// Flat repositories have no component: "<uri> ./ Packages"
+		source.Suite, source.Component, _ = strings.Cut(fields[2], "/")
+	}
+
+	if len(fields) > 4 {
+		source.Architecture = fields[3]
+	}
+
+	return source
+}
+
+// aptSourceReleases runs apt-cache policy without packages and returns the
+// release fields of every package file, keyed by policySource.key
+func (h *Handler) aptSourceReleases(ctx context.Context) (map[string]releaseFields, error) {
+	output, err := h.sysCalls.execCommand(ctx, "env", "LC_ALL=C", "LANG=C", "apt-cache", "policy")
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to execute apt-cache policy")
+	}
+
+	return parseSourceReleases(string(output)), nil
+}
+
+// parseSourceReleases parses the package files section of apt-cache policy:
+//
+//	500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+//	    release v=24.04,o=Ubuntu,a=noble-security,n=noble,l=Ubuntu,c=main,b=amd64
+//	    origin security.ubuntu.com
+func parseSourceReleases(output string) map[string]releaseFields {
+	releases := make(map[string]releaseFields)
+	key := ""
+
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		switch {
+		case line == "Pinned packages:":
+			return releases
+		case strings.HasPrefix(line, "release "):
+			if key == "" {
+				continue
+			}
+
+			release := releases[key]
+			for _, field := range strings.Split(strings.TrimPrefix(line, "release "), ",") {
+				name, value, _ := strings.Cut(field, "=")
+
+				switch name {
+				case "o":
+					release.Origin = value
+				case "l":
+					release.Label = value
+				case "a":
+					release.Archive = value
+				case "n":
+					release.Codename = value
+				case "c":
+					release.Component = value
+				}
+			}
+
+			releases[key] = release
+		case strings.HasPrefix(line, "origin "):
+			if key == "" {
+				continue
+			}
+
+			release := releases[key]
+			release.Site = strings.TrimPrefix(line, "origin ")
+			releases[key] = release
+		default:
+			fields := strings.Fields(line)
+			if len(fields) < 5 || fields[len(fields)-1] != "Packages" {
+				key = ""
+
+				continue
+			}
+
+			source := parsePolicySource(fields)
+			key = source.key()
+			// Package files without release fields are described by the source line
+			releases[key] = source.release()
+		}
+	}
+
+	return releases
+}
+
+// key identifies the package file of a source across apt-cache policy outputs
+func (s policySource) key() string {
+	return s.URI + " " + s.Suite + "/" + s.Component + " " + s.Architecture
+}
+
+// host returns the host name of the source URI
+func (s policySource) host() string {
+	_, rest, ok := strings.Cut(s.URI, "://")
+	if !ok {
+		return ""
+	}
+
+	host, _, _ := strings.Cut(rest, "/")
+
+	return host
+}
+
+// path returns the path of the source URI, without leading and trailing slashes
+func (s policySource) path() string {
+	_, rest, ok := strings.Cut(s.URI, "://")
+	if !ok {
+		return ""
+	}
+
+	_, uriPath, _ := strings.Cut(rest, "/")
+
+	return strings.Trim(uriPath, "/")
+}
+
+// release describes a source without known release fields by its suite, component and URI
+func (s policySource) release() releaseFields {
+	return releaseFields{Archive: s.Suite, Component: s.Component, Site: s.host(), Path: s.path()}
+}
+
+// targetReleases returns the releases the target version is available from,
+// falling back to the candidate when the target is not in the version table.
+// Sources without known release fields are described by suite, component and host.
+func (p *packagePolicy) targetReleases(target string, sourceReleases map[string]releaseFields) []releaseFields {
+	v, ok := p.version(target)
+	if !ok {
+		v, ok = p.version(p.Candidate)
+		if !ok {
+			return nil
+		}
+	}
+
+	var releases []releaseFields
+	for _, source := range v.Sources {
+		if source.Suite == "" {
+			// The dpkg status file
+			continue
+		}
+
+		release, ok := sourceReleases[source.key()]
+		if !ok {
+			release = source.release()
+		}
+
+		releases = append(releases, release)
+	}
+
+	return releases
+}
+
+// version returns the version table entry of the given version
+func (p *packagePolicy) version(v string) (policyVersion, bool) {
+	for _, pv := range p.Versions {
+		if pv.Version == v {
+			return pv, true
+		}
+	}
+
+	return policyVersion{}, false
+}
+
+// policyValue returns the value of a "Key: value" line, empty for "(none)"
+func policyValue(line string) string {
+	_, value, _ := strings.Cut(line, ":")
+	value = strings.TrimSpace(value)
+
+	if value == "(none)" {
+		return ""
+	}
+
+	return value
+}
+
+// indentation returns the number of leading spaces of line
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,115 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicyOutput = `openssl:
+  Installed: 3.0.13-0ubuntu3.4
+  Candidate: 3.0.13-0ubuntu3.5
+  Version table:
+     3.0.13-0ubuntu3.5 500
+        500 http://archive.ubuntu.com/ubuntu noble-updates/main amd64 Packages
+        500 http://security.ubuntu.com/ubuntu noble-security/main amd64 Packages
+ *** 3.0.13-0ubuntu3.4 100
+        100 /var/lib/dpkg/status
+     3.0.13-0ubuntu3 500
+        500 http://archive.ubuntu.com/ubuntu noble/main amd64 Packages
+htop:
+  Installed: 3.3.0-4
+  Candidate: 3.3.0-4build1
+  Version table:
+     3.3.0-4build1 500
+        500 http://archive.ubuntu.com/ubuntu noble-updates/universe amd64 Packages
+ *** 3.3.0-4 100
+        100 /var/lib/dpkg/status
+docker-ce:
+  Installed: 5:27.3.0-1~ubuntu.24.04~noble
+  Candidate: 5:27.3.1-1~ubuntu.24.04~noble
+  Version table:
+     5:27.3.1-1~ubuntu.24.04~noble 500
+        500 https://download.docker.com/linux/ubuntu noble/stable amd64 Packages
+ *** 5:27.3.0-1~ubuntu.24.04~noble 100
+        100 /var/lib/dpkg/status
+`
+
+// TestClassifyUpdatesBatched ensures all packages are classified with a single batched apt-cache policy call,
+// plus one call for the release fields of the package files
+func TestClassifyUpdatesBatched(t *testing.T) {
+	sysCalls := &countingSystemCalls{systemCalls: &policySystemCalls{}}
+	handler := &Handler{sysCalls: sysCalls}
+
+	classified := handler.classifyUpdates(context.Background(), []UpdateInfo{
+		{Name: "openssl", Target: "3.0.13-0ubuntu3.5"},
+		{Name: "htop", Target: "3.3.0-4build1"},
+		{Name: "docker-ce", Target: "5:27.3.1-1~ubuntu.24.04~noble"},
+		{Name: "unknown-package", Target: "1.0"},
+	})
+
+	assert.Equal(t, 2, sysCalls.calls, "expected a single batched apt-cache policy call")
+	assert.True(t, classified["openssl"][UpdateTypeSecurity])
+	assert.False(t, classified["openssl"][UpdateTypeOptional])
+	assert.True(t, classified["htop"][UpdateTypeOptional])
+	assert.False(t, classified["docker-ce"][UpdateTypeSecurity])
+	// Third-party suites are not recommended by pocket
+	assert.False(t, classified["docker-ce"][UpdateTypeRecommended])
+	assert.True(t, classified["unknown-package"][UpdateTypeAll])
+}
+
+// TestParsePolicy ensures the version table of every package is parsed
+func TestParsePolicy(t *testing.T) {
+	policies := parsePolicy("N: Unable to locate package foo\n" + testPolicyOutput)
+	require.Len(t, policies, 3)
+
+	openssl := policies["openssl"]
+	assert.Equal(t, "3.0.13-0ubuntu3.4", openssl.Installed)
+	assert.Equal(t, "3.0.13-0ubuntu3.5", openssl.Candidate)
+	require.Len(t, openssl.Versions, 3)
+
+	candidate, ok := openssl.version("3.0.13-0ubuntu3.5")
+	require.True(t, ok)
+	assert.Equal(t, 500, candidate.Priority)
+	assert.Equal(t, []policySource{
+		{Priority: 500, URI: "http://archive.ubuntu.com/ubuntu", Suite: "noble-updates", Component: "main", Architecture: "amd64"},
+		{Priority: 500, URI: "http://security.ubuntu.com/ubuntu", Suite: "noble-security", Component: "main", Architecture: "amd64"},
+	}, candidate.Sources)
+
+	installed, ok := openssl.version("3.0.13-0ubuntu3.4")
+	require.True(t, ok)
+	assert.True(t, installed.Installed)
+	assert.Equal(t, []policySource{{Priority: 100, URI: "/var/lib/dpkg/status"}}, installed.Sources)
+}
+
+// countingSystemCalls counts the commands executed through the wrapped systemCalls
+type countingSystemCalls struct {
+	systemCalls
+	calls int
+}
+
+func (c *countingSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	c.calls++
+
+	return c.systemCalls.execCommand(ctx, name, args...)
+}
@@ -0,0 +1,103 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// rebootRequiredFile is created by package maintainer scripts through update-notifier
+	rebootRequiredFile = "/var/run/reboot-required"
+	// rebootRequiredPkgsFile lists the packages that requested the reboot, one per line
+	rebootRequiredPkgsFile = "/var/run/reboot-required.pkgs"
+)
+
+// RebootStatus describes a pending reboot requested by installed packages
+type RebootStatus struct {
+	Required bool     `json:"required"`
+	Since    int64    `json:"since"` // Unix timestamp the reboot was first requested, 0 if not required
+	Packages []string `json:"packages"`
+}
+
+// sectionReboot names the reboot status in AllUpdatesResult.SectionErrors
+const sectionReboot = "reboot_required"
+
+// GetRebootRequired returns whether a reboot is pending, since when and which packages requested it
+func (h *Handler) GetRebootRequired(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.RebootRequired == nil {
+		return nil, result.sectionError(sectionReboot, "failed to check reboot status")
+	}
+
+	return result.RebootRequired, nil
+}
+
+// rebootStatus reads the reboot-required flag files
+func (h *Handler) rebootStatus() (*RebootStatus, error) {
+	status := &RebootStatus{Packages: []string{}}
+
+	info, err := h.sysCalls.stat(rebootRequiredFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return status, nil
+		}
+
+		return nil, errs.Wrap(err, "failed to stat reboot-required file")
+	}
+
+	status.Required = true
+	status.Since = info.ModTime().Unix()
+
+	data, err := h.readFile(rebootRequiredPkgsFile)
+	if err != nil {
+		// Not every package that requests a reboot names itself
+		if errors.Is(err, fs.ErrNotExist) {
+			return status, nil
+		}
+
+		return nil, errs.Wrap(err, "failed to read reboot-required packages")
+	}
+
+	// The file is appended to without checking for duplicates
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		pkg := strings.TrimSpace(sc.Text())
+		if pkg == "" || seen[pkg] {
+			continue
+		}
+
+		seen[pkg] = true
+		status.Packages = append(status.Packages, pkg)
+	}
+
+	return status, nil
+}
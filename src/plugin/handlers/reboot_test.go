@@ -0,0 +1,97 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRebootStatus ensures the reboot-required flag files are reported
+func TestRebootStatus(t *testing.T) {
+	requested := time.Date(2026, 3, 2, 6, 25, 0, 0, time.UTC)
+
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/run/reboot-required":      {Data: []byte("*** System restart required ***\n"), ModTime: requested},
+		"var/run/reboot-required.pkgs": {Data: []byte("linux-image-6.8.0-51-generic\nlibc6\nlinux-image-6.8.0-51-generic\n\n")},
+	}}}
+
+	res, err := handler.GetRebootRequired(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &RebootStatus{
+		Required: true,
+		Since:    requested.Unix(),
+		Packages: []string{"linux-image-6.8.0-51-generic", "libc6"},
+	}, res)
+
+	// Requested without naming a package
+	handler.sysCalls = &mockSystemCalls{mockFiles: mockFiles{
+		"var/run/reboot-required": &fstest.MapFile{ModTime: requested},
+	}}
+	status, err := handler.rebootStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Required)
+	assert.Empty(t, status.Packages)
+
+	handler.sysCalls = &mockSystemCalls{}
+	status, err = handler.rebootStatus()
+	require.NoError(t, err)
+	assert.Equal(t, &RebootStatus{Packages: []string{}}, status)
+}
+
+// TestGetAllUpdatesRebootRequired ensures the reboot status is part of the JSON output
+func TestGetAllUpdatesRebootRequired(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{"var/run/reboot-required": {Data: []byte{}}},
+		output:    "curl/noble-updates 8.5.0-2ubuntu10.6]\n",
+	}}
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+
+	result := res.(*AllUpdatesResult)
+	require.NotNil(t, result.RebootRequired)
+	assert.True(t, result.RebootRequired.Required)
+}
+
+// TestRebootStatusFailed ensures a reboot status that cannot be read is reported rather than left out
+func TestRebootStatusFailed(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"var/run/reboot-required":      {Data: []byte{}},
+		"var/run/reboot-required.pkgs": {Mode: fs.ModeDir},
+	}}}
+
+	_, err := handler.GetRebootRequired(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to check reboot status")
+	assert.Contains(t, err.Error(), "failed to read reboot-required packages")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+
+	result := res.(*AllUpdatesResult)
+	assert.Nil(t, result.RebootRequired)
+	assert.Contains(t, result.SectionErrors["reboot_required"], "failed to read reboot-required packages")
+}
@@ -0,0 +1,149 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// repositoryUnknown groups the updates whose repository could not be determined
+const repositoryUnknown = "unknown"
+
+// RepositoryUpdates contains the pending updates coming from a single repository
+type RepositoryUpdates struct {
+	Repository    string   `json:"repository"`
+	Count         int      `json:"count"`
+	SecurityCount int      `json:"security_count"`
+	List          []string `json:"list"`
+}
+
+// RepositoryDiscoveryEntry is a single low-level discovery row describing a repository with pending updates
+type RepositoryDiscoveryEntry struct {
+	Name string `json:"{#REPO.NAME}"`
+}
+
+// DiscoverRepositories returns Zabbix low-level discovery data for the repositories pending updates come from
+func (h *Handler) DiscoverRepositories(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	entries := make([]RepositoryDiscoveryEntry, 0, len(result.RepositoryUpdates))
+	for _, repo := range result.RepositoryUpdates {
+		entries = append(entries, RepositoryDiscoveryEntry{Name: repo.Repository})
+	}
+
+	return entries, nil
+}
+
+// repositoryUpdates groups the updates by their repository
+func repositoryUpdates(updates []UpdateInfo, categories map[string]map[UpdateType]bool) []RepositoryUpdates {
+	groups := make(map[string]*RepositoryUpdates)
+	for _, pkg := range updates {
+		name := pkg.Repository
+		if name == "" {
+			name = repositoryUnknown
+		}
+
+		group, ok := groups[name]
+		if !ok {
+			group = &RepositoryUpdates{Repository: name, List: []string{}}
+			groups[name] = group
+		}
+
+		group.Count++
+		group.List = append(group.List, pkg.Name)
+
+		if categories[pkg.Name][UpdateTypeSecurity] {
+			group.SecurityCount++
+		}
+	}
+
+	result := make([]RepositoryUpdates, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].Repository < result[j].Repository
+	})
+
+	return result
+}
+
+// updateRepository returns the repository of the most significant pocket the
+// target version is available from, see updatePocket
+func updateRepository(releases []releaseFields) string {
+	best, rank := -1, len(pocketOrder)+1
+	for i, release := range releases {
+		r := slices.Index(pocketOrder, releasePocket(release))
+		if r < 0 {
+			r = len(pocketOrder)
+		}
+
+		if r < rank {
+			best, rank = i, r
+		}
+	}
+
+	if best < 0 {
+		return ""
+	}
+
+	return releases[best].repository()
+}
+
+// repository names the repository of a release: "ppa:<owner>/<name>" for
+// Launchpad PPAs, otherwise the origin (or label, or host) followed by the
+// suite, e.g. "Ubuntu noble-security" or "Docker noble"
+func (r releaseFields) repository() string {
+	if strings.HasSuffix(r.Site, "launchpad.net") || strings.HasSuffix(r.Site, "launchpadcontent.net") {
+		// <owner>/<name>/ubuntu
+		parts := strings.Split(r.Path, "/")
+		if len(parts) >= 2 {
+			return "ppa:" + parts[0] + "/" + parts[1]
+		}
+	}
+
+	suite := r.Archive
+	if suite == "" {
+		suite = r.Codename
+	}
+
+	name := r.Origin
+	if name == "" {
+		name = r.Label
+	}
+
+	if name == "" {
+		name = r.Site
+	}
+
+	return strings.TrimSpace(name + " " + suite)
+}
@@ -0,0 +1,118 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestReleaseRepository ensures repositories are named by origin and suite, and PPAs by owner and name
+func TestReleaseRepository(t *testing.T) {
+	sources := parseSourceReleases(testSourcesPolicyOutput)
+
+	site, repoPath := listsFileRepository("ppa.launchpadcontent.net_ondrej_nginx_ubuntu_dists_noble_InRelease")
+	assert.Equal(t, "ppa.launchpadcontent.net", site)
+	assert.Equal(t, "ondrej/nginx/ubuntu", repoPath)
+
+	tests := []struct {
+		name    string
+		release releaseFields
+		want    string
+	}{
+		{
+			name:    "distribution pocket",
+			release: sources["http://security.ubuntu.com/ubuntu noble-security/main amd64"],
+			want:    "Ubuntu noble-security",
+		},
+		{
+			name:    "ppa from apt-cache policy",
+			release: sources["https://ppa.launchpadcontent.net/ondrej/php/ubuntu noble/main amd64"],
+			want:    "ppa:ondrej/php",
+		},
+		{
+			name:    "ppa from the lists directory",
+			release: releaseFields{Origin: "LP-PPA-ondrej-nginx", Archive: "noble", Site: site, Path: repoPath},
+			want:    "ppa:ondrej/nginx",
+		},
+		{
+			name:    "without release fields",
+			release: sources["http://mirror.example.com/ubuntu noble-security/main amd64"],
+			want:    "mirror.example.com noble-security",
+		},
+		{
+			name:    "inst line label",
+			release: releaseFields{Label: "Docker CE", Archive: "noble"},
+			want:    "Docker CE noble",
+		},
+		{
+			name:    "codename only",
+			release: releaseFields{Origin: "Docker", Codename: "noble"},
+			want:    "Docker noble",
+		},
+		{
+			name: "nothing known",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.release.repository())
+		})
+	}
+}
+
+// TestUpdateRepository ensures an update is attributed to the repository of its most significant pocket
+func TestUpdateRepository(t *testing.T) {
+	releases := []releaseFields{
+		{Origin: "Ubuntu", Archive: "noble-updates", Component: "main"},
+		{Origin: "Ubuntu", Archive: "noble-security", Component: "main"},
+	}
+	assert.Equal(t, "Ubuntu noble-security", updateRepository(releases))
+
+	assert.Equal(t, "Docker noble", updateRepository([]releaseFields{{Origin: "Docker", Archive: "noble"}}))
+	assert.Empty(t, updateRepository(nil))
+}
+
+// TestRepositoryUpdates ensures updates are grouped by repository, most updates first
+func TestRepositoryUpdates(t *testing.T) {
+	updates := []UpdateInfo{
+		{Name: "openssl", Repository: "Ubuntu noble-security"},
+		{Name: "docker-ce", Repository: "Docker noble"},
+		{Name: "libssl3t64", Repository: "Ubuntu noble-security"},
+		{Name: "htop", Repository: "Ubuntu noble-updates"},
+		{Name: "local-tool"},
+	}
+	categories := map[string]map[UpdateType]bool{
+		"openssl":    {UpdateTypeAll: true, UpdateTypeSecurity: true},
+		"libssl3t64": {UpdateTypeAll: true, UpdateTypeSecurity: true},
+		"htop":       {UpdateTypeAll: true, UpdateTypeRecommended: true},
+	}
+
+	assert.Equal(t, []RepositoryUpdates{
+		{Repository: "Ubuntu noble-security", Count: 2, SecurityCount: 2, List: []string{"openssl", "libssl3t64"}},
+		{Repository: "Docker noble", Count: 1, List: []string{"docker-ce"}},
+		{Repository: "Ubuntu noble-updates", Count: 1, List: []string{"htop"}},
+		{Repository: repositoryUnknown, Count: 1, List: []string{"local-tool"}},
+	}, repositoryUpdates(updates, categories))
+
+	assert.Empty(t, repositoryUpdates(nil, nil))
+}
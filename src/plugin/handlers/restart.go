@@ -0,0 +1,307 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	procDir = "/proc"
+	// dpkgInfoDir holds the <package>.list files with the paths installed by every package
+	dpkgInfoDir = "/var/lib/dpkg/info"
+	// deletedSuffix marks mappings of files that were unlinked or replaced after being mapped
+	deletedSuffix = " (deleted)"
+)
+
+// executableDirs are the directories of the executables checked for deleted
+// mappings, other executable mappings are JIT code or IPC
+//
+//nolint:gochecknoglobals // lookup table.
+var executableDirs = []string{
+	"/lib/", "/lib32/", "/lib64/", "/libx32/", "/bin/", "/sbin/",
+	"/usr/lib/", "/usr/lib32/", "/usr/lib64/", "/usr/libx32/", "/usr/libexec/", "/usr/bin/", "/usr/sbin/",
+	"/usr/local/lib/", "/usr/local/bin/", "/usr/local/sbin/", "/opt/",
+}
+
+// RestartProcess is a process still using deleted or replaced files
+type RestartProcess struct {
+	PID      int      `json:"pid"`
+	Command  string   `json:"command"`
+	Unit     string   `json:"unit,omitempty"` // systemd unit the process belongs to
+	Files    []string `json:"files"`
+	Packages []string `json:"packages"` // Packages that own the files
+}
+
+// RestartStatus lists the processes and services that need a restart to use upgraded files
+type RestartStatus struct {
+	ProcessCount int              `json:"process_count"`
+	ServiceCount int              `json:"service_count"`
+	Processes    []RestartProcess `json:"processes"`
+	Services     []string         `json:"services"`
+	Packages     []string         `json:"packages"`
+	// SkippedProcesses is the number of processes whose mappings could not be read,
+	// checking processes of other users requires root or CAP_SYS_PTRACE
+	SkippedProcesses int `json:"skipped_processes"`
+}
+
+// sectionRestart names the process scan in AllUpdatesResult.SectionErrors
+const sectionRestart = "restart_required"
+
+// GetRestartRequired returns the processes and systemd services still running deleted or replaced
+// shared objects or executables, e.g. nginx still using libssl after openssl was upgraded
+func (h *Handler) GetRestartRequired(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.restart == nil {
+		return nil, result.sectionError(sectionRestart, "failed to check processes needing restart")
+	}
+
+	return result.restart, nil
+}
+
+// restartStatus scans the memory mappings of all processes for deleted files
+func (h *Handler) restartStatus(ctx context.Context) (*RestartStatus, error) {
+	entries, err := h.sysCalls.readDir(procDir)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to list processes")
+	}
+
+	status := &RestartStatus{
+		Processes: []RestartProcess{},
+		Services:  []string{},
+		Packages:  []string{},
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil, errs.Wrap(ctx.Err(), "process scan interrupted")
+		}
+
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+
+		files, err := h.deletedMappings(pid)
+		if err != nil {
+			// The process exited or belongs to another user
+			status.SkippedProcesses++
+
+			continue
+		}
+
+		if len(files) == 0 {
+			continue
+		}
+
+		status.Processes = append(status.Processes, RestartProcess{
+			PID:     pid,
+			Command: h.processCommand(pid),
+			Unit:    h.processUnit(pid),
+			Files:   files,
+		})
+	}
+
+	sort.Slice(status.Processes, func(i, j int) bool { return status.Processes[i].PID < status.Processes[j].PID })
+
+	owners := h.fileOwners(status.Processes)
+
+	for i, proc := range status.Processes {
+		status.Processes[i].Packages = []string{}
+
+		for _, file := range proc.Files {
+			for _, pkg := range owners[file] {
+				if !slices.Contains(status.Processes[i].Packages, pkg) {
+					status.Processes[i].Packages = append(status.Processes[i].Packages, pkg)
+				}
+
+				if !slices.Contains(status.Packages, pkg) {
+					status.Packages = append(status.Packages, pkg)
+				}
+			}
+		}
+
+		if proc.Unit != "" && strings.HasSuffix(proc.Unit, ".service") && !slices.Contains(status.Services, proc.Unit) {
+			status.Services = append(status.Services, proc.Unit)
+		}
+	}
+
+	sort.Strings(status.Services)
+	sort.Strings(status.Packages)
+
+	status.ProcessCount = len(status.Processes)
+	status.ServiceCount = len(status.Services)
+
+	return status, nil
+}
+
+// deletedMappings returns the deleted shared objects and executables mapped by a process:
+//
+//	7f1c2a000000-7f1c2a0b4000 r--p 00000000 08:01 1835 /usr/lib/x86_64-linux-gnu/libssl.so.3 (deleted)
+func (h *Handler) deletedMappings(pid int) ([]string, error) {
+	data, err := h.readFile(path.Join(procDir, strconv.Itoa(pid), "maps"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		// The path is the sixth field and may contain spaces
+		fields := strings.SplitN(sc.Text(), " ", 6)
+		if len(fields) < 6 {
+			continue
+		}
+
+		name, deleted := strings.CutSuffix(strings.TrimLeft(fields[5], " "), deletedSuffix)
+		if !deleted || !isExecutableMapping(name, fields[1]) || slices.Contains(files, name) {
+			continue
+		}
+
+		files = append(files, name)
+	}
+
+	return files, nil
+}
+
+// isExecutableMapping reports whether a mapped file is a shared object (*.so*)
+// or an executable. Data files such as /usr/lib/locale/locale-archive are never
+// mapped with execute permission.
+func isExecutableMapping(name, perms string) bool {
+	base := path.Base(name)
+	if strings.HasSuffix(base, ".so") || strings.Contains(base, ".so.") {
+		return true
+	}
+
+	if !strings.Contains(perms, "x") {
+		return false
+	}
+
+	for _, dir := range executableDirs {
+		if strings.HasPrefix(name, dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// processCommand returns the command name of a process
+func (h *Handler) processCommand(pid int) string {
+	data, err := h.readFile(path.Join(procDir, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+// processUnit returns the systemd unit of a process from its control group:
+//
+//	0::/system.slice/nginx.service             (cgroup v2)
+//	1:name=systemd:/system.slice/nginx.service (cgroup v1)
+func (h *Handler) processUnit(pid int) string {
+	data, err := h.readFile(path.Join(procDir, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		parts := strings.SplitN(sc.Text(), ":", 3)
+		if len(parts) < 3 || (parts[0] != "0" && parts[1] != "name=systemd") {
+			continue
+		}
+
+		// The innermost service or scope, e.g. user@1000.service/app.slice/foo.service
+		elements := strings.Split(parts[2], "/")
+		for i := len(elements) - 1; i >= 0; i-- {
+			if strings.HasSuffix(elements[i], ".service") || strings.HasSuffix(elements[i], ".scope") {
+				return elements[i]
+			}
+		}
+	}
+
+	return ""
+}
+
+// fileOwners maps the files used by the processes to the packages that install them,
+// from the dpkg file lists. Paths are also matched across the merged /usr.
+func (h *Handler) fileOwners(procs []RestartProcess) map[string][]string {
+	owners := make(map[string][]string)
+
+	wanted := make(map[string]string)
+	for _, proc := range procs {
+		for _, file := range proc.Files {
+			wanted[file] = file
+			if alias, ok := strings.CutPrefix(file, "/usr"); ok {
+				wanted[alias] = file
+			} else {
+				wanted["/usr"+file] = file
+			}
+		}
+	}
+
+	if len(wanted) == 0 {
+		return owners
+	}
+
+	entries, err := h.sysCalls.readDir(dpkgInfoDir)
+	if err != nil {
+		return owners
+	}
+
+	for _, entry := range entries {
+		pkg, ok := strings.CutSuffix(entry.Name(), ".list")
+		if !ok {
+			continue
+		}
+
+		data, err := h.readFile(path.Join(dpkgInfoDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		pkg, _, _ = strings.Cut(pkg, ":")
+
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			file, ok := wanted[sc.Text()]
+			if ok && !slices.Contains(owners[file], pkg) {
+				owners[file] = append(owners[file], pkg)
+			}
+		}
+	}
+
+	return owners
+}
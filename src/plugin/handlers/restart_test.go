@@ -0,0 +1,156 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRestartStatus ensures processes mapping deleted libraries are found on a fake procfs
+func TestRestartStatus(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		// nginx master and worker still use the old libssl
+		"proc/812/comm":   {Data: []byte("nginx\n")},
+		"proc/812/cgroup": {Data: []byte("0::/system.slice/nginx.service\n")},
+		"proc/812/maps": {Data: []byte(
+			"55d0c8a00000-55d0c8a2e000 r--p 00000000 08:01 1311  /usr/sbin/nginx\n" +
+				"7f1c2a000000-7f1c2a0b4000 r--p 00000000 08:01 1835  /usr/lib/x86_64-linux-gnu/libssl.so.3 (deleted)\n" +
+				"7f1c2a0b4000-7f1c2a10f000 r-xp 000b4000 08:01 1835  /usr/lib/x86_64-linux-gnu/libssl.so.3 (deleted)\n" +
+				// Data files replaced by an upgrade do not require a restart
+				"7f1c29000000-7f1c29400000 r--p 00000000 08:01 1402  /usr/lib/locale/locale-archive (deleted)\n" +
+				"7f1c29400000-7f1c29407000 r--s 00000000 08:01 1403  /usr/lib/x86_64-linux-gnu/gconv/gconv-modules.cache (deleted)\n" +
+				"7f1c2b000000-7f1c2b001000 rw-s 00000000 00:01 4097  /dev/zero (deleted)\n" +
+				"7f1c2c000000-7f1c2c001000 rw-s 00000000 00:05 4098  /memfd:pulseaudio (deleted)\n" +
+				"7ffd1c5e0000-7ffd1c601000 rw-p 00000000 00:00 0     [stack]\n"),
+		},
+		"proc/813/comm":   {Data: []byte("nginx\n")},
+		"proc/813/cgroup": {Data: []byte("0::/system.slice/nginx.service\n")},
+		"proc/813/maps": {Data: []byte(
+			"7f1c2a000000-7f1c2a0b4000 r--p 00000000 08:01 1835  /usr/lib/x86_64-linux-gnu/libssl.so.3 (deleted)\n" +
+				"7f1c2d000000-7f1c2d100000 r--p 00000000 08:01 1836  /usr/lib/x86_64-linux-gnu/libcrypto.so.3 (deleted)\n"),
+		},
+		// A user session process running a replaced binary, cgroup v1
+		"proc/2290/comm": {Data: []byte("tmux: server\n")},
+		"proc/2290/cgroup": {Data: []byte("12:pids:/user.slice/user-1000.slice\n" +
+			"1:name=systemd:/user.slice/user-1000.slice/session-3.scope\n")},
+		"proc/2290/maps": {Data: []byte("55a1b2c00000-55a1b2c80000 r-xp 00000000 08:01 2101  /usr/bin/tmux (deleted)\n")},
+		// Up to date process
+		"proc/1/comm":   {Data: []byte("systemd\n")},
+		"proc/1/cgroup": {Data: []byte("0::/init.scope\n")},
+		"proc/1/maps":   {Data: []byte("55d0c8a00000-55d0c8a2e000 r--p 00000000 08:01 1200  /usr/lib/systemd/systemd\n")},
+		// Kernel thread and a process of another user whose maps cannot be read
+		"proc/2/comm":    {Data: []byte("kthreadd\n")},
+		"proc/2/maps":    {Data: []byte{}},
+		"proc/4711/comm": {Data: []byte("sshd\n")},
+		"proc/self":      {Mode: fs.ModeSymlink, Data: []byte("1")},
+		"proc/meminfo":   {Data: []byte("MemTotal: 1 kB\n")},
+
+		"var/lib/dpkg/info/libssl3t64:amd64.list": {Data: []byte("/.\n/usr\n/usr/lib\n/usr/lib/x86_64-linux-gnu\n" +
+			"/usr/lib/x86_64-linux-gnu/libcrypto.so.3\n/usr/lib/x86_64-linux-gnu/libssl.so.3\n")},
+		"var/lib/dpkg/info/libssl3t64:amd64.md5sums": {Data: []byte("d41d8cd98f00b204e9800998ecf8427e  usr/lib/x86_64-linux-gnu/libssl.so.3\n")},
+		// Listed without the merged /usr prefix
+		"var/lib/dpkg/info/tmux.list": {Data: []byte("/.\n/bin\n/bin/tmux\n")},
+	}}}
+
+	res, err := handler.GetRestartRequired(context.Background(), nil)
+	require.NoError(t, err)
+
+	status := res.(*RestartStatus)
+	assert.Equal(t, []RestartProcess{
+		{
+			PID:      812,
+			Command:  "nginx",
+			Unit:     "nginx.service",
+			Files:    []string{"/usr/lib/x86_64-linux-gnu/libssl.so.3"},
+			Packages: []string{"libssl3t64"},
+		},
+		{
+			PID:      813,
+			Command:  "nginx",
+			Unit:     "nginx.service",
+			Files:    []string{"/usr/lib/x86_64-linux-gnu/libssl.so.3", "/usr/lib/x86_64-linux-gnu/libcrypto.so.3"},
+			Packages: []string{"libssl3t64"},
+		},
+		{
+			PID:      2290,
+			Command:  "tmux: server",
+			Unit:     "session-3.scope",
+			Files:    []string{"/usr/bin/tmux"},
+			Packages: []string{"tmux"},
+		},
+	}, status.Processes)
+	assert.Equal(t, 3, status.ProcessCount)
+	assert.Equal(t, []string{"nginx.service"}, status.Services)
+	assert.Equal(t, 1, status.ServiceCount)
+	assert.Equal(t, []string{"libssl3t64", "tmux"}, status.Packages)
+	assert.Equal(t, 1, status.SkippedProcesses)
+}
+
+// TestIsExecutableMapping ensures only shared objects and executables are checked
+func TestIsExecutableMapping(t *testing.T) {
+	tests := []struct {
+		name  string
+		perms string
+		want  bool
+	}{
+		{name: "/usr/lib/x86_64-linux-gnu/libssl.so.3", perms: "r--p", want: true},
+		{name: "/usr/lib/x86_64-linux-gnu/security/pam_unix.so", perms: "r-xp", want: true},
+		{name: "/home/user/.cache/plugin.so", perms: "r--p", want: true},
+		{name: "/usr/bin/tmux", perms: "r-xp", want: true},
+		{name: "/usr/bin/tmux", perms: "r--p", want: false},
+		{name: "/usr/lib/locale/locale-archive", perms: "r--p", want: false},
+		{name: "/usr/lib/x86_64-linux-gnu/gconv/gconv-modules.cache", perms: "r--s", want: false},
+		{name: "/usr/share/icons/hicolor/icon-theme.cache", perms: "r--p", want: false},
+		{name: "/tmp/jit-4711.dump", perms: "r-xp", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isExecutableMapping(tt.name, tt.perms), tt.name+" "+tt.perms)
+	}
+}
+
+// TestRestartStatusClean ensures an empty result is returned when nothing needs a restart
+func TestRestartStatusClean(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"proc/1/maps": {Data: []byte("55d0c8a00000-55d0c8a2e000 r--p 00000000 08:01 1200  /usr/lib/systemd/systemd\n")},
+	}}}
+
+	status, err := handler.restartStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &RestartStatus{Processes: []RestartProcess{}, Services: []string{}, Packages: []string{}}, status)
+}
+
+// TestGetRestartRequiredFailed ensures a failed process scan is reported by the item and in the snapshot
+func TestGetRestartRequiredFailed(t *testing.T) {
+	// No /proc to list
+	handler := &Handler{sysCalls: &mockSystemCalls{}}
+
+	_, err := handler.GetRestartRequired(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to list processes")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.(*AllUpdatesResult).SectionErrors, "restart_required")
+}
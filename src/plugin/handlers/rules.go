@@ -0,0 +1,223 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// categoryNameRe restricts category names to what can be passed as an item key parameter
+//
+//nolint:gochecknoglobals // compiled once.
+var categoryNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ClassificationRule assigns the updates matching all of its patterns to a category.
+// Patterns are regular expressions, empty patterns match everything.
+type ClassificationRule struct {
+	Name      string
+	Category  string
+	Origin    string
+	Label     string
+	Suite     string // Matched against the suite and the codename
+	Component string
+	Section   string
+	Package   string
+}
+
+// classificationRule is a ClassificationRule with compiled patterns
+type classificationRule struct {
+	name      string
+	category  UpdateType
+	origin    *regexp.Regexp
+	label     *regexp.Regexp
+	suite     *regexp.Regexp
+	component *regexp.Regexp
+	section   *regexp.Regexp
+	pkg       *regexp.Regexp
+}
+
+// ValidateRules returns an error if any of the rules is invalid
+func ValidateRules(rules []ClassificationRule) error {
+	_, err := compileRules(rules)
+
+	return err
+}
+
+// SetRules replaces the classification rules applied by the next update check.
+// Rules are applied in the given order on top of the built-in classification.
+func (h *Handler) SetRules(rules []ClassificationRule) error {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+
+	h.rules.Store(&compiled)
+
+	return nil
+}
+
+// compileRules validates the rules and compiles their patterns
+func compileRules(rules []ClassificationRule) ([]classificationRule, error) {
+	compiled := make([]classificationRule, 0, len(rules))
+
+	for _, rule := range rules {
+		if !categoryNameRe.MatchString(rule.Category) {
+			return nil, errs.Errorf("rule %q: invalid category %q", rule.Name, rule.Category)
+		}
+
+		if rule.Category == string(UpdateTypeAll) {
+			return nil, errs.Errorf("rule %q: category %q is reserved", rule.Name, rule.Category)
+		}
+
+		patterns := []string{rule.Origin, rule.Label, rule.Suite, rule.Component, rule.Section, rule.Package}
+		if !slices.ContainsFunc(patterns, func(p string) bool { return p != "" }) {
+			return nil, errs.Errorf("rule %q: no pattern set", rule.Name)
+		}
+
+		c := classificationRule{name: rule.Name, category: UpdateType(rule.Category)}
+
+		for _, field := range []struct {
+			name    string
+			pattern string
+			re      **regexp.Regexp
+		}{
+			{"Origin", rule.Origin, &c.origin},
+			{"Label", rule.Label, &c.label},
+			{"Suite", rule.Suite, &c.suite},
+			{"Component", rule.Component, &c.component},
+			{"Section", rule.Section, &c.section},
+			{"Package", rule.Package, &c.pkg},
+		} {
+			if field.pattern == "" {
+				continue
+			}
+
+			re, err := regexp.Compile(field.pattern)
+			if err != nil {
+				return nil, errs.Wrapf(err, "rule %q: invalid %s pattern", rule.Name, field.name)
+			}
+
+			*field.re = re
+		}
+
+		compiled = append(compiled, c)
+	}
+
+	return compiled, nil
+}
+
+// classificationRules returns the configured rules
+func (h *Handler) classificationRules() []classificationRule {
+	rules := h.rules.Load()
+	if rules == nil {
+		return nil
+	}
+
+	return *rules
+}
+
+// ruleCategories returns the categories of the configured rules that are not built in
+func (h *Handler) ruleCategories() []UpdateType {
+	var categories []UpdateType
+	for _, rule := range h.classificationRules() {
+		if isBuiltinUpdateType(rule.category) || slices.Contains(categories, rule.category) {
+			continue
+		}
+
+		categories = append(categories, rule.category)
+	}
+
+	return categories
+}
+
+// checkUpdateType returns an error if the update type is neither built in nor
+// the category of a configured rule
+func (h *Handler) checkUpdateType(updateType UpdateType) error {
+	if isBuiltinUpdateType(updateType) || slices.Contains(h.ruleCategories(), updateType) {
+		return nil
+	}
+
+	return errs.Errorf("unknown update type %q", updateType)
+}
+
+// isBuiltinUpdateType reports whether the category is one of the built-in update types
+func isBuiltinUpdateType(updateType UpdateType) bool {
+	switch updateType {
+	case UpdateTypeAll, UpdateTypeSecurity, UpdateTypeRecommended, UpdateTypeOptional:
+		return true
+	}
+
+	return false
+}
+
+// matches reports whether the package, with the section and the releases of its
+// target version, matches all patterns of the rule. Release patterns have to
+// match the same release.
+func (r *classificationRule) matches(name, section string, releases []releaseFields) bool {
+	if !matchPattern(r.pkg, name) || !matchPattern(r.section, section) {
+		return false
+	}
+
+	if r.origin == nil && r.label == nil && r.suite == nil && r.component == nil {
+		return true
+	}
+
+	for _, release := range releases {
+		if matchPattern(r.origin, release.Origin) &&
+			matchPattern(r.label, release.Label) &&
+			(matchPattern(r.suite, release.Archive) || matchPattern(r.suite, release.Codename)) &&
+			matchPattern(r.component, release.Component) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPattern reports whether the value matches the pattern, a nil pattern matches everything
+func matchPattern(re *regexp.Regexp, value string) bool {
+	return re == nil || re.MatchString(value)
+}
+
+// applyRules adds the categories of the matching rules to the categories of an update.
+// Rules assigning the security category are reported in SecurityRule unless a
+// built-in rule already matched.
+func (h *Handler) applyRules(update *UpdateInfo, categories map[UpdateType]bool, section string, releases []releaseFields) {
+	name, _, _ := strings.Cut(update.Name, ":")
+
+	for _, rule := range h.classificationRules() {
+		if !rule.matches(name, section, releases) {
+			continue
+		}
+
+		categories[rule.category] = true
+
+		if rule.category == UpdateTypeSecurity && update.SecurityRule == "" {
+			update.SecurityRule = "rule:" + rule.name
+		}
+
+		if !isBuiltinUpdateType(rule.category) && !slices.Contains(update.Categories, string(rule.category)) {
+			update.Categories = append(update.Categories, string(rule.category))
+		}
+	}
+}
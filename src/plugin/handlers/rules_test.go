@@ -0,0 +1,117 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompileRules ensures invalid rules are rejected
+func TestCompileRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ClassificationRule
+		wantErr bool
+	}{
+		{name: "origin rule", rule: ClassificationRule{Name: "docker", Category: "vendor-critical", Origin: "^Docker$"}},
+		{name: "built-in category", rule: ClassificationRule{Name: "pgdg", Category: "security", Suite: "-pgdg$"}},
+		{name: "no pattern", rule: ClassificationRule{Name: "empty", Category: "third-party"}, wantErr: true},
+		{name: "missing category", rule: ClassificationRule{Name: "docker", Origin: "Docker"}, wantErr: true},
+		{name: "reserved category", rule: ClassificationRule{Name: "docker", Category: "all", Origin: "Docker"}, wantErr: true},
+		{name: "category with spaces", rule: ClassificationRule{Name: "docker", Category: "third party", Origin: "Docker"}, wantErr: true},
+		{name: "invalid pattern", rule: ClassificationRule{Name: "grafana", Category: "third-party", Package: "grafana("}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules([]ClassificationRule{tt.rule})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+// TestClassifyUpdatesRules ensures configured rules add their categories on top of the built-in ones
+func TestClassifyUpdatesRules(t *testing.T) {
+	handler := &Handler{sysCalls: &policySystemCalls{}}
+	require.NoError(t, handler.SetRules([]ClassificationRule{
+		{Name: "docker", Category: "vendor-critical", Suite: "^noble$", Component: "^stable$"},
+		{Name: "htop", Category: "security", Package: "^htop$"},
+		{Name: "libs", Category: "third-party", Section: "^libs$"},
+	}))
+
+	updates := []UpdateInfo{
+		{Name: "openssl", Target: "3.0.13-0ubuntu3.5"},
+		{Name: "htop", Target: "3.3.0-4build1"},
+		{Name: "docker-ce:amd64", Target: "5:27.3.1-1~ubuntu.24.04~noble"},
+	}
+	classified := handler.classifyUpdates(context.Background(), updates)
+
+	assert.True(t, classified["docker-ce:amd64"][UpdateType("vendor-critical")])
+	assert.Equal(t, []string{"vendor-critical"}, updates[2].Categories)
+	assert.False(t, classified["openssl"][UpdateType("vendor-critical")])
+	assert.Empty(t, updates[0].Categories)
+
+	assert.True(t, classified["htop"][UpdateTypeSecurity])
+	assert.True(t, classified["htop"][UpdateTypeOptional])
+	assert.Equal(t, "rule:htop", updates[1].SecurityRule)
+	assert.Empty(t, updates[1].Categories)
+
+	// Built-in rules take precedence in the reported security rule
+	assert.Equal(t, "ubuntu-security", updates[0].SecurityRule)
+
+	// Sections are unknown to apt-cache policy
+	assert.False(t, classified["openssl"][UpdateType("third-party")])
+}
+
+// TestGetAllUpdatesRuleCategories ensures rule categories are reported in the JSON and per-type items
+func TestGetAllUpdatesRuleCategories(t *testing.T) {
+	handler := &Handler{
+		sysCalls: newMockSystemCalls("grafana/stable 11.3.0]\npostgresql-16/noble-pgdg 16.5-1.pgdg24.04+1]\ncurl/noble-updates 8.5.0-2ubuntu10.6]\n", nil),
+	}
+	require.NoError(t, handler.SetRules([]ClassificationRule{
+		{Name: "grafana", Category: "third-party", Package: "^grafana"},
+		{Name: "pgdg", Category: "vendor-critical", Package: "^postgresql-"},
+		{Name: "unused", Category: "unused", Package: "^nothing$"},
+	}))
+
+	result, err := handler.getAllUpdates(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, result.CategoryUpdates, 3)
+	assert.Equal(t, []string{"grafana"}, result.CategoryUpdates["third-party"].List)
+	assert.Equal(t, 1, result.CategoryUpdates["vendor-critical"].Count)
+	assert.Equal(t, 0, result.CategoryUpdates["unused"].Count)
+	assert.NotNil(t, result.CategoryUpdates["unused"].Details)
+
+	list, err := handler.GetUpdateList(context.Background(), map[string]string{"type": "vendor-critical"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"postgresql-16"}, list)
+
+	entries, err := handler.DiscoverUpdates(context.Background(), map[string]string{"type": "third-party"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "grafana", entries.([]UpdateDiscoveryEntry)[0].Name)
+
+	_, err = handler.GetUpdateList(context.Background(), map[string]string{"type": "vendor"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,357 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// sourcesListFile is the main APT sources list in the one-line format
+	sourcesListFile = "/etc/apt/sources.list"
+	// sourcesPartsDir holds further sources, *.list in the one-line and *.sources in the deb822 format
+	sourcesPartsDir = "/etc/apt/sources.list.d"
+)
+
+// Formats of the APT sources files, see sources.list(5)
+const (
+	sourcesFormatOneLine = "one-line"
+	sourcesFormatDeb822  = "deb822"
+)
+
+// uriSchemeRe matches the scheme of a repository URI, which tells sources from
+// commented-out prose such as "# deb-src lines are disabled by default"
+//
+//nolint:gochecknoglobals // compiled once.
+var uriSchemeRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// AptSource is a repository configured in the APT sources, one per type and URI
+type AptSource struct {
+	File          string   `json:"file"`
+	Format        string   `json:"format"` // one-line or deb822
+	Type          string   `json:"type"`   // deb or deb-src
+	URI           string   `json:"uri"`
+	Suites        []string `json:"suites"`
+	Components    []string `json:"components"`
+	Architectures []string `json:"architectures"` // Empty for every configured architecture
+	SignedBy      string   `json:"signed_by"`     // Keyring files or fingerprints, "inline" for an embedded key
+	Enabled       bool     `json:"enabled"`
+}
+
+// SourcesResult is the inventory of the configured APT sources
+type SourcesResult struct {
+	Count        int         `json:"count"`
+	EnabledCount int         `json:"enabled_count"`
+	Sources      []AptSource `json:"sources"`
+}
+
+// SourceDiscoveryEntry is a single low-level discovery row describing a configured APT source
+type SourceDiscoveryEntry struct {
+	File       string `json:"{#SOURCE.FILE}"`
+	Type       string `json:"{#SOURCE.TYPE}"`
+	URI        string `json:"{#SOURCE.URI}"`
+	Suites     string `json:"{#SOURCE.SUITES}"`     // Space separated
+	Components string `json:"{#SOURCE.COMPONENTS}"` // Space separated
+	SignedBy   string `json:"{#SOURCE.SIGNED_BY}"`
+	Enabled    string `json:"{#SOURCE.ENABLED}"` // "1" for enabled sources, "0" otherwise
+}
+
+// GetSources returns the repositories configured in the APT sources, read when requested
+func (h *Handler) GetSources(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	sources, err := h.aptSources()
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read APT sources")
+	}
+
+	result := &SourcesResult{Count: len(sources), Sources: sources}
+	for _, source := range sources {
+		if source.Enabled {
+			result.EnabledCount++
+		}
+	}
+
+	return result, nil
+}
+
+// DiscoverSources returns Zabbix low-level discovery data for the configured APT sources
+func (h *Handler) DiscoverSources(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	sources, err := h.aptSources()
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read APT sources")
+	}
+
+	entries := make([]SourceDiscoveryEntry, 0, len(sources))
+	for _, source := range sources {
+		enabled := "0"
+		if source.Enabled {
+			enabled = "1"
+		}
+
+		entries = append(entries, SourceDiscoveryEntry{
+			File:       source.File,
+			Type:       source.Type,
+			URI:        source.URI,
+			Suites:     strings.Join(source.Suites, " "),
+			Components: strings.Join(source.Components, " "),
+			SignedBy:   source.SignedBy,
+			Enabled:    enabled,
+		})
+	}
+
+	return entries, nil
+}
+
+// aptSources reads sourcesListFile and the *.list and *.sources files of
+// sourcesPartsDir in the order apt does. Files apt ignores, e.g. *.save or
+// *.distUpgrade, are skipped.
+func (h *Handler) aptSources() ([]AptSource, error) {
+	files := []string{sourcesListFile}
+
+	entries, err := h.sysCalls.readDir(sourcesPartsDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, errs.Wrapf(err, "failed to list %s", sourcesPartsDir)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && (path.Ext(name) == ".list" || path.Ext(name) == ".sources") {
+			files = append(files, path.Join(sourcesPartsDir, name))
+		}
+	}
+
+	sources := []AptSource{}
+
+	for _, file := range files {
+		data, err := h.readFile(file)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Recent releases only ship sources.list.d/*.sources
+				continue
+			}
+
+			return nil, err
+		}
+
+		if path.Ext(file) == ".sources" {
+			parsed, err := parseDeb822Sources(file, data)
+			if err != nil {
+				return nil, err
+			}
+
+			sources = append(sources, parsed...)
+
+			continue
+		}
+
+		sources = append(sources, parseOneLineSources(file, data)...)
+	}
+
+	return sources, nil
+}
+
+// parseOneLineSources parses a sources file in the one-line format:
+//
+//	deb [arch=amd64 signed-by=/etc/apt/keyrings/docker.asc] https://download.docker.com/linux/ubuntu noble stable
+//
+// Commented out source lines are reported as disabled sources.
+func parseOneLineSources(file string, data []byte) []AptSource {
+	var sources []AptSource
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+
+		enabled := !strings.HasPrefix(line, "#")
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		// The rest of the line after a # is a comment
+		line, _, _ = strings.Cut(line, "#")
+
+		source, ok := parseOneLineSource(line)
+		if !ok {
+			continue
+		}
+
+		source.File = file
+		source.Enabled = enabled
+		sources = append(sources, source)
+	}
+
+	return sources
+}
+
+// parseOneLineSource parses a single "type [options] uri suite [component...]" line
+func parseOneLineSource(line string) (AptSource, bool) {
+	sourceType, rest := line, ""
+	if end := strings.IndexAny(line, " \t"); end >= 0 {
+		sourceType, rest = line[:end], line[end:]
+	}
+
+	if sourceType != "deb" && sourceType != "deb-src" {
+		return AptSource{}, false
+	}
+
+	source := AptSource{
+		Format:        sourcesFormatOneLine,
+		Type:          sourceType,
+		Components:    []string{},
+		Architectures: []string{},
+	}
+
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "[") {
+		options, after, ok := strings.Cut(rest[1:], "]")
+		if !ok {
+			return AptSource{}, false
+		}
+
+		for _, option := range strings.Fields(options) {
+			name, value, _ := strings.Cut(option, "=")
+
+			switch name {
+			case "arch":
+				source.Architectures = strings.Split(value, ",")
+			case "signed-by":
+				source.SignedBy = strings.ReplaceAll(value, ",", " ")
+			}
+		}
+
+		rest = after
+	}
+
+	uri, rest, ok := cutSourceURI(strings.TrimSpace(rest))
+	if !ok {
+		return AptSource{}, false
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 1 {
+		return AptSource{}, false
+	}
+
+	source.URI = uri
+	source.Suites = []string{fields[0]}
+	source.Components = append(source.Components, fields[1:]...)
+
+	return source, true
+}
+
+// cutSourceURI cuts the repository URI off the front of a source line and reports
+// whether it has a scheme. cdrom URIs carry the disc label in brackets, spaces included:
+//
+//	cdrom:[Debian GNU/Linux 12.5.0 _Bookworm_ - Official amd64 DVD Binary-1]/ bookworm main
+func cutSourceURI(line string) (string, string, bool) {
+	start := 0
+	if strings.HasPrefix(line, "cdrom:[") {
+		end := strings.Index(line, "]")
+		if end < 0 {
+			return "", "", false
+		}
+
+		start = end
+	}
+
+	uri, rest := line, ""
+	if end := strings.IndexAny(line[start:], " \t"); end >= 0 {
+		uri, rest = line[:start+end], line[start+end:]
+	}
+
+	if !uriSchemeRe.MatchString(uri) {
+		return "", "", false
+	}
+
+	return uri, rest, true
+}
+
+// parseDeb822Sources parses a sources file in the deb822 format, one entry per
+// type and URI of every stanza:
+//
+//	Types: deb
+//	URIs: http://archive.ubuntu.com/ubuntu/
+//	Suites: noble noble-updates noble-backports
+//	Components: main restricted universe multiverse
+//	Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+func parseDeb822Sources(file string, data []byte) ([]AptSource, error) {
+	var sources []AptSource
+
+	err := parseDeb822(bytes.NewReader(data), func(stanza deb822Stanza) error {
+		enabled := parseAptBool(stanza.field("Enabled"), true)
+
+		signedBy := strings.Join(strings.Fields(stanza.field("Signed-By")), " ")
+		if strings.Contains(signedBy, "BEGIN PGP PUBLIC KEY BLOCK") {
+			signedBy = "inline"
+		}
+
+		for _, sourceType := range strings.Fields(stanza.field("Types")) {
+			for _, uri := range strings.Fields(stanza.field("URIs")) {
+				sources = append(sources, AptSource{
+					File:          file,
+					Format:        sourcesFormatDeb822,
+					Type:          sourceType,
+					URI:           uri,
+					Suites:        fieldList(stanza.field("Suites")),
+					Components:    fieldList(stanza.field("Components")),
+					Architectures: fieldList(stanza.field("Architectures")),
+					SignedBy:      signedBy,
+					Enabled:       enabled,
+				})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to parse %s", file)
+	}
+
+	return sources, nil
+}
+
+// field returns the value of a field, matching its name case-insensitively as apt does for sources
+func (s deb822Stanza) field(name string) string {
+	if value, ok := s[name]; ok {
+		return value
+	}
+
+	for key, value := range s {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// fieldList splits a whitespace separated field value, never returning nil
+func fieldList(value string) []string {
+	fields := strings.Fields(value)
+	if fields == nil {
+		return []string{}
+	}
+
+	return fields
+}
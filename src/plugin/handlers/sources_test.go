@@ -0,0 +1,167 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSourcesList = `# See http://help.ubuntu.com/community/UpgradeNotes for how to upgrade to
+# newer versions of the distribution.
+deb http://archive.ubuntu.com/ubuntu/ jammy main restricted # main archive
+# deb-src http://archive.ubuntu.com/ubuntu/ jammy main restricted
+deb [arch=amd64,arm64 signed-by=/etc/apt/keyrings/docker.asc] https://download.docker.com/linux/ubuntu jammy stable
+deb [ trusted=yes ] file:/srv/repo ./
+deb	http://deb.example.com/debian	bookworm	main
+
+# deb-src lines are disabled by default, uncomment them to fetch the sources
+# deb cdrom:[Debian GNU/Linux 12.5.0 _Bookworm_ - Official amd64 DVD Binary-1 with firmware 20240210-11:28]/ bookworm main
+`
+
+	testUbuntuSources = `Types: deb
+URIs: http://archive.ubuntu.com/ubuntu/
+Suites: noble noble-updates noble-backports
+Components: main restricted universe multiverse
+Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+
+# Security
+types: deb deb-src
+URIs: http://security.ubuntu.com/ubuntu/
+Suites: noble-security
+Components: main restricted universe multiverse
+Signed-By: /usr/share/keyrings/ubuntu-archive-keyring.gpg
+`
+
+	testPPASources = `Types: deb
+URIs: https://ppa.launchpadcontent.net/ondrej/php/ubuntu/
+Suites: noble
+Components: main
+Architectures: amd64
+Enabled: false
+Signed-By:
+ -----BEGIN PGP PUBLIC KEY BLOCK-----
+ .
+ mQINBGYo2CkBEAC1VCBhYFeRnfqgI2/ONoSoI6V9GMXqvIWGaGpBHyB44eHqhrUQ
+ -----END PGP PUBLIC KEY BLOCK-----
+`
+)
+
+// TestAptSources ensures one-line and deb822 sources are read from sources.list and sources.list.d
+func TestAptSources(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"etc/apt/sources.list":                             {Data: []byte(testSourcesList)},
+		"etc/apt/sources.list.d/ubuntu.sources":            {Data: []byte(testUbuntuSources)},
+		"etc/apt/sources.list.d/ondrej-ubuntu-php.sources": {Data: []byte(testPPASources)},
+		"etc/apt/sources.list.d/old.list.distUpgrade":      {Data: []byte("deb http://old.example.com/ stable main\n")},
+	}}}
+
+	sources, err := handler.aptSources()
+	require.NoError(t, err)
+
+	ubuntu := []string{"main", "restricted", "universe", "multiverse"}
+	keyring := "/usr/share/keyrings/ubuntu-archive-keyring.gpg"
+
+	assert.Equal(t, []AptSource{
+		{
+			File: "/etc/apt/sources.list", Format: "one-line", Type: "deb", URI: "http://archive.ubuntu.com/ubuntu/",
+			Suites: []string{"jammy"}, Components: []string{"main", "restricted"}, Architectures: []string{}, Enabled: true,
+		},
+		{
+			File: "/etc/apt/sources.list", Format: "one-line", Type: "deb-src", URI: "http://archive.ubuntu.com/ubuntu/",
+			Suites: []string{"jammy"}, Components: []string{"main", "restricted"}, Architectures: []string{},
+		},
+		{
+			File: "/etc/apt/sources.list", Format: "one-line", Type: "deb", URI: "https://download.docker.com/linux/ubuntu",
+			Suites: []string{"jammy"}, Components: []string{"stable"}, Architectures: []string{"amd64", "arm64"},
+			SignedBy: "/etc/apt/keyrings/docker.asc", Enabled: true,
+		},
+		{
+			File: "/etc/apt/sources.list", Format: "one-line", Type: "deb", URI: "file:/srv/repo",
+			Suites: []string{"./"}, Components: []string{}, Architectures: []string{}, Enabled: true,
+		},
+		{
+			File: "/etc/apt/sources.list", Format: "one-line", Type: "deb", URI: "http://deb.example.com/debian",
+			Suites: []string{"bookworm"}, Components: []string{"main"}, Architectures: []string{}, Enabled: true,
+		},
+		{
+			File: "/etc/apt/sources.list", Format: "one-line", Type: "deb",
+			URI:    "cdrom:[Debian GNU/Linux 12.5.0 _Bookworm_ - Official amd64 DVD Binary-1 with firmware 20240210-11:28]/",
+			Suites: []string{"bookworm"}, Components: []string{"main"}, Architectures: []string{},
+		},
+		{
+			File: "/etc/apt/sources.list.d/ondrej-ubuntu-php.sources", Format: "deb822", Type: "deb",
+			URI: "https://ppa.launchpadcontent.net/ondrej/php/ubuntu/", Suites: []string{"noble"},
+			Components: []string{"main"}, Architectures: []string{"amd64"}, SignedBy: "inline",
+		},
+		{
+			File: "/etc/apt/sources.list.d/ubuntu.sources", Format: "deb822", Type: "deb",
+			URI: "http://archive.ubuntu.com/ubuntu/", Suites: []string{"noble", "noble-updates", "noble-backports"},
+			Components: ubuntu, Architectures: []string{}, SignedBy: keyring, Enabled: true,
+		},
+		{
+			File: "/etc/apt/sources.list.d/ubuntu.sources", Format: "deb822", Type: "deb",
+			URI: "http://security.ubuntu.com/ubuntu/", Suites: []string{"noble-security"},
+			Components: ubuntu, Architectures: []string{}, SignedBy: keyring, Enabled: true,
+		},
+		{
+			File: "/etc/apt/sources.list.d/ubuntu.sources", Format: "deb822", Type: "deb-src",
+			URI: "http://security.ubuntu.com/ubuntu/", Suites: []string{"noble-security"},
+			Components: ubuntu, Architectures: []string{}, SignedBy: keyring, Enabled: true,
+		},
+	}, sources)
+}
+
+// TestParseAptBool ensures the deb822 Enabled field accepts apt's boolean spellings
+func TestParseAptBool(t *testing.T) {
+	for _, value := range []string{"no", "False", "off", "disable", "without", "0"} {
+		assert.False(t, parseAptBool(value, true), value)
+	}
+
+	for _, value := range []string{"yes", "TRUE", "on", "enable", "with", "1"} {
+		assert.True(t, parseAptBool(value, false), value)
+	}
+
+	assert.True(t, parseAptBool("", true))
+	assert.False(t, parseAptBool("maybe", false))
+}
+
+// TestDiscoverSources ensures every source becomes a discovery row, without a sources.list
+func TestDiscoverSources(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{mockFiles: mockFiles{
+		"etc/apt/sources.list.d/ondrej-ubuntu-php.sources": {Data: []byte(testPPASources)},
+	}}}
+
+	entries, err := handler.DiscoverSources(context.Background(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []SourceDiscoveryEntry{{
+		File:       "/etc/apt/sources.list.d/ondrej-ubuntu-php.sources",
+		Type:       "deb",
+		URI:        "https://ppa.launchpadcontent.net/ondrej/php/ubuntu/",
+		Suites:     "noble",
+		Components: "main",
+		SignedBy:   "inline",
+		Enabled:    "0",
+	}}, entries)
+}
@@ -0,0 +1,440 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+const (
+	// unattendedBinary is installed by the unattended-upgrades package
+	unattendedBinary = "/usr/bin/unattended-upgrade"
+	// unattendedLogFile is written by every unattended-upgrades run, rotated weekly to .1
+	unattendedLogFile = "/var/log/unattended-upgrades/unattended-upgrades.log"
+	// unattendedStampFile is touched by apt.systemd.daily after each run
+	unattendedStampFile = "/var/lib/apt/periodic/unattended-upgrades-stamp"
+	// osReleaseFile provides the values of the ${distro_id} and ${distro_codename} variables
+	osReleaseFile = "/etc/os-release"
+
+	// unattendedTimeLayout is the timestamp format of the Python logging module
+	unattendedTimeLayout = "2006-01-02 15:04:05,000"
+	// unattendedStartMessage starts the log of a run
+	unattendedStartMessage = "Starting unattended upgrades script"
+)
+
+// Results of the last unattended-upgrades run
+const (
+	unattendedSuccess    = "success"
+	unattendedFailed     = "failed"
+	unattendedIncomplete = "incomplete" // Still running, or stopped without logging a result
+)
+
+// sectionUnattended names the unattended-upgrades status in AllUpdatesResult.SectionErrors
+const sectionUnattended = "unattended"
+
+// Reasons for unattended-upgrades to skip a pending security update
+const (
+	skipOriginNotAllowed = "origin-not-allowed"
+	skipBlacklisted      = "blacklisted"
+	skipNotWhitelisted   = "not-whitelisted"
+)
+
+// UnattendedStatus describes the configuration and the last run of unattended-upgrades
+type UnattendedStatus struct {
+	Installed         bool     `json:"installed"`
+	Enabled           bool     `json:"enabled"`  // Installed and APT::Periodic::Unattended-Upgrade is set
+	LastRun           int64    `json:"last_run"` // Unix timestamp the last run started, 0 if it never ran
+	LastRunResult     string   `json:"last_run_result"`
+	LastRunSuccess    bool     `json:"last_run_success"`
+	LastRunErrors     []string `json:"last_run_errors"`
+	InstalledPackages []string `json:"installed_packages"` // Packages upgraded by the last run
+	Origins           []string `json:"origins"`            // Allowed origins as patterns, variables expanded
+	PackageBlacklist  []string `json:"package_blacklist"`
+
+	SkippedSecurityUpdatesCount int             `json:"skipped_security_updates_count"`
+	SkippedSecurityUpdates      []SkippedUpdate `json:"skipped_security_updates"`
+}
+
+// SkippedUpdate is a pending update unattended-upgrades is configured not to install
+type SkippedUpdate struct {
+	Name       string `json:"name"`
+	Target     string `json:"target_version"`
+	Repository string `json:"repository,omitempty"`
+	Reason     string `json:"reason"` // origin-not-allowed, blacklisted or not-whitelisted
+}
+
+// unattendedRun is the outcome of an unattended-upgrades run, as logged
+type unattendedRun struct {
+	Started  time.Time
+	Result   string
+	Errors   []string
+	Packages []string
+}
+
+// unattendedConfig is the part of the APT configuration unattended-upgrades uses
+type unattendedConfig struct {
+	Enabled   bool
+	Origins   []string
+	Blacklist []string
+	Whitelist []string
+}
+
+// GetUnattendedUpgrades returns whether unattended-upgrades is enabled, how its last
+// run went and which pending security updates it is configured to skip
+func (h *Handler) GetUnattendedUpgrades(ctx context.Context, metricParams map[string]string, extraParams ...string) (any, error) {
+	result, err := h.snapshot(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to check APT updates")
+	}
+
+	if result.unattended == nil {
+		return nil, result.sectionError(sectionUnattended, "failed to check unattended-upgrades status")
+	}
+
+	return result.unattended, nil
+}
+
+// unattendedStatus reads the unattended-upgrades configuration and log and matches
+// the pending security updates against the configuration
+func (h *Handler) unattendedStatus(ctx context.Context, security []UpdateInfo) (*UnattendedStatus, error) {
+	status := &UnattendedStatus{
+		LastRunErrors:          []string{},
+		InstalledPackages:      []string{},
+		SkippedSecurityUpdates: []SkippedUpdate{},
+	}
+
+	_, err := h.sysCalls.stat(unattendedBinary)
+	status.Installed = err == nil
+
+	config, err := h.unattendedConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = status.Installed && config.Enabled
+	status.Origins = config.Origins
+	status.PackageBlacklist = config.Blacklist
+
+	run, err := h.lastUnattendedRun()
+	if err != nil {
+		return nil, err
+	}
+
+	if run != nil {
+		status.LastRun = run.Started.Unix()
+		status.LastRunResult = run.Result
+		status.LastRunSuccess = run.Result == unattendedSuccess
+		status.LastRunErrors = append(status.LastRunErrors, run.Errors...)
+
+		// A failed run may have installed none or only some of its packages
+		if status.LastRunSuccess {
+			status.InstalledPackages = append(status.InstalledPackages, run.Packages...)
+		}
+	} else if info, err := h.sysCalls.stat(unattendedStampFile); err == nil {
+		// The log was rotated away, the stamp still tells when it last ran
+		status.LastRun = info.ModTime().Unix()
+	}
+
+	for _, pkg := range security {
+		reason := config.skipReason(pkg)
+		if reason == "" {
+			continue
+		}
+
+		status.SkippedSecurityUpdates = append(status.SkippedSecurityUpdates, SkippedUpdate{
+			Name:       pkg.Name,
+			Target:     pkg.Target,
+			Repository: pkg.Repository,
+			Reason:     reason,
+		})
+	}
+
+	status.SkippedSecurityUpdatesCount = len(status.SkippedSecurityUpdates)
+
+	return status, nil
+}
+
+// unattendedConfig reads the Unattended-Upgrade settings through apt-config, so the
+// files in /etc/apt/apt.conf.d override each other as they do for apt
+func (h *Handler) unattendedConfig(ctx context.Context) (*unattendedConfig, error) {
+	aptConfig, err := h.readAptConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := h.distroVariables()
+
+	config := &unattendedConfig{
+		Enabled:   periodicEnabled(aptConfig.value("APT::Periodic::Unattended-Upgrade")),
+		Origins:   []string{},
+		Blacklist: aptConfig.list("Unattended-Upgrade::Package-Blacklist"),
+		Whitelist: aptConfig.list("Unattended-Upgrade::Package-Whitelist"),
+	}
+
+	// Allowed-Origins entries are "<origin>:<archive>", unattended-upgrades converts them to patterns
+	for _, origin := range aptConfig.list("Unattended-Upgrade::Allowed-Origins") {
+		id, archive, ok := strings.Cut(origin, ":")
+		if !ok {
+			id, archive, _ = strings.Cut(origin, " ")
+		}
+
+		config.Origins = append(config.Origins, "o="+vars.Replace(id)+",a="+vars.Replace(strings.TrimSpace(archive)))
+	}
+
+	for _, pattern := range aptConfig.list("Unattended-Upgrade::Origins-Pattern") {
+		config.Origins = append(config.Origins, vars.Replace(pattern))
+	}
+
+	return config, nil
+}
+
+// skipReason returns why unattended-upgrades would not install the update, or an empty string
+func (c *unattendedConfig) skipReason(pkg UpdateInfo) string {
+	name, _, _ := strings.Cut(pkg.Name, ":")
+
+	if matchPackageList(c.Blacklist, name) {
+		return skipBlacklisted
+	}
+
+	if len(c.Whitelist) > 0 && !matchPackageList(c.Whitelist, name) {
+		return skipNotWhitelisted
+	}
+
+	for _, pattern := range c.Origins {
+		for _, release := range pkg.releases {
+			if matchOriginPattern(pattern, release) {
+				return ""
+			}
+		}
+	}
+
+	// Releases taken from the Inst line carry no origin, whether they are allowed is unknown
+	if len(pkg.releases) == 0 || slices.ContainsFunc(pkg.releases, func(r releaseFields) bool { return r.Origin == "" }) {
+		return ""
+	}
+
+	return skipOriginNotAllowed
+}
+
+// matchPackageList reports whether a package name matches one of the regular
+// expressions of a package list, anchored at the start as unattended-upgrades does
+func matchPackageList(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")")
+		if err != nil {
+			if strings.HasPrefix(name, pattern) {
+				return true
+			}
+
+			continue
+		}
+
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchOriginPattern reports whether a release matches an Origins-Pattern entry:
+// comma separated key=value pairs, with shell wildcards in the values and "\,"
+// for literal commas, e.g. "origin=Debian,codename=bookworm,label=Debian-Security"
+func matchOriginPattern(pattern string, release releaseFields) bool {
+	for _, part := range splitOriginPattern(pattern) {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return false
+		}
+
+		var field string
+		switch key {
+		case "o", "origin":
+			field = release.Origin
+		case "l", "label":
+			field = release.Label
+		case "a", "archive", "suite":
+			field = release.Archive
+		case "n", "codename":
+			field = release.Codename
+		case "c", "component":
+			field = release.Component
+		case "site":
+			field = release.Site
+		default:
+			return false
+		}
+
+		if matched, err := path.Match(value, field); err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// splitOriginPattern splits an Origins-Pattern entry at the unescaped commas
+func splitOriginPattern(pattern string) []string {
+	var (
+		parts []string
+		part  strings.Builder
+	)
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern) && pattern[i+1] == ',':
+			part.WriteByte(',')
+			i++
+		case pattern[i] == ',':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(pattern[i])
+		}
+	}
+
+	return append(parts, part.String())
+}
+
+// periodicEnabled reports whether an APT::Periodic interval enables the job:
+// a number of days or "always"
+func periodicEnabled(value string) bool {
+	if value == "always" {
+		return true
+	}
+
+	days, err := strconv.Atoi(value)
+
+	return err == nil && days > 0
+}
+
+// distroVariables returns the replacer of the variables unattended-upgrades expands
+// in origins, taken from os-release: ${distro_id} (e.g. Ubuntu) and ${distro_codename}
+func (h *Handler) distroVariables() *strings.Replacer {
+	osRelease := make(map[string]string)
+
+	data, err := h.readFile(osReleaseFile)
+	if err == nil {
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		for sc.Scan() {
+			key, value, ok := strings.Cut(sc.Text(), "=")
+			if ok {
+				osRelease[key] = strings.Trim(value, `"'`)
+			}
+		}
+	}
+
+	// lsb_release -i, which unattended-upgrades uses, prints the capitalized ID
+	id := osRelease["ID"]
+	if id != "" {
+		id = strings.ToUpper(id[:1]) + id[1:]
+	}
+
+	codename := osRelease["VERSION_CODENAME"]
+	if codename == "" {
+		codename = osRelease["UBUNTU_CODENAME"]
+	}
+
+	return strings.NewReplacer("${distro_id}", id, "${distro_codename}", codename)
+}
+
+// lastUnattendedRun returns the last run logged in the unattended-upgrades log or,
+// right after rotation, in the previous log. It returns nil if no run is logged.
+func (h *Handler) lastUnattendedRun() (*unattendedRun, error) {
+	for _, name := range []string{unattendedLogFile, unattendedLogFile + ".1"} {
+		data, err := h.readFile(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, errs.Wrap(err, "failed to read unattended-upgrades log")
+		}
+
+		if run := parseUnattendedLog(string(data)); run != nil {
+			return run, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// parseUnattendedLog parses the last run of an unattended-upgrades log:
+//
+//	2026-10-15 06:25:12,345 INFO Starting unattended upgrades script
+//	2026-10-15 06:25:12,346 INFO Allowed origins are: o=Ubuntu,a=noble, o=Ubuntu,a=noble-security
+//	2026-10-15 06:25:15,100 INFO Packages that will be upgraded: libssl3t64 openssl
+//	2026-10-15 06:25:40,200 INFO All upgrades installed
+func parseUnattendedLog(log string) *unattendedRun {
+	var run *unattendedRun
+
+	sc := bufio.NewScanner(strings.NewReader(log))
+	for sc.Scan() {
+		// <date> <time> <level> <message>
+		fields := strings.SplitN(sc.Text(), " ", 4)
+		if len(fields) < 4 {
+			continue
+		}
+
+		level, message := fields[2], fields[3]
+
+		if message == unattendedStartMessage {
+			started, err := time.ParseInLocation(unattendedTimeLayout, fields[0]+" "+fields[1], time.Local)
+			if err != nil {
+				continue
+			}
+
+			run = &unattendedRun{Started: started, Result: unattendedIncomplete}
+
+			continue
+		}
+
+		if run == nil {
+			continue
+		}
+
+		switch {
+		case level == "ERROR" || level == "CRITICAL":
+			run.Errors = append(run.Errors, message)
+			run.Result = unattendedFailed
+		case strings.HasPrefix(message, "Packages that will be upgraded:"):
+			_, packages, _ := strings.Cut(message, ":")
+			run.Packages = strings.Fields(packages)
+		case strings.HasPrefix(message, "All upgrades installed"),
+			strings.HasPrefix(message, "No packages found that can be upgraded unattended"):
+			if run.Result != unattendedFailed {
+				run.Result = unattendedSuccess
+			}
+		}
+	}
+
+	return run
+}
@@ -0,0 +1,216 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAptConfigDump = `APT "";
+APT::Architecture "amd64";
+APT::Periodic "";
+APT::Periodic::Update-Package-Lists "1";
+APT::Periodic::Unattended-Upgrade "1";
+Unattended-Upgrade "";
+Unattended-Upgrade::Allowed-Origins "";
+Unattended-Upgrade::Allowed-Origins:: "${distro_id}:${distro_codename}";
+Unattended-Upgrade::Allowed-Origins:: "${distro_id}:${distro_codename}-security";
+Unattended-Upgrade::Allowed-Origins:: "${distro_id}ESMApps:${distro_codename}-apps-security";
+Unattended-Upgrade::Origins-Pattern "";
+Unattended-Upgrade::Origins-Pattern:: "site=ppa.launchpadcontent.net,o=LP-PPA-ondrej-*";
+Unattended-Upgrade::Package-Blacklist "";
+Unattended-Upgrade::Package-Blacklist:: "linux-";
+Unattended-Upgrade::Automatic-Reboot "false";
+`
+
+const testUnattendedLog = `2026-10-14 06:12:01,101 INFO Starting unattended upgrades script
+2026-10-14 06:12:01,102 INFO Allowed origins are: o=Ubuntu,a=noble, o=Ubuntu,a=noble-security
+2026-10-14 06:12:05,230 INFO Packages that will be upgraded: curl libcurl4t64
+2026-10-14 06:12:05,231 INFO Writing dpkg log to /var/log/unattended-upgrades/unattended-upgrades-dpkg.log
+2026-10-14 06:12:30,400 ERROR Installing the upgrades failed!
+2026-10-14 06:12:30,401 ERROR error message: 'E:Sub-process /usr/bin/dpkg returned an error code (1)'
+2026-10-15 06:25:12,345 INFO Starting unattended upgrades script
+2026-10-15 06:25:12,346 INFO Allowed origins are: o=Ubuntu,a=noble, o=Ubuntu,a=noble-security
+2026-10-15 06:25:12,346 INFO Initial blacklist: linux-
+2026-10-15 06:25:15,100 INFO Packages that will be upgraded: libssl3t64 openssl
+2026-10-15 06:25:15,101 INFO Writing dpkg log to /var/log/unattended-upgrades/unattended-upgrades-dpkg.log
+2026-10-15 06:25:40,200 INFO All upgrades installed
+`
+
+// TestParseAptConfig ensures scalar and list options are parsed case-insensitively
+func TestParseAptConfig(t *testing.T) {
+	config := parseAptConfig(testAptConfigDump)
+
+	assert.Equal(t, "1", config.value("APT::Periodic::Unattended-Upgrade"))
+	assert.Equal(t, "1", config.value("apt::periodic::unattended-upgrade"))
+	assert.Equal(t, "false", config.value("Unattended-Upgrade::Automatic-Reboot"))
+	assert.Empty(t, config.value("APT::Periodic::AutocleanInterval"))
+	assert.Equal(t, []string{"linux-"}, config.list("Unattended-Upgrade::Package-Blacklist"))
+	assert.Len(t, config.list("Unattended-Upgrade::Allowed-Origins"), 3)
+	assert.Equal(t, []string{}, config.list("Unattended-Upgrade::Package-Whitelist"))
+}
+
+// TestParseUnattendedLog ensures only the last run of the log is reported
+func TestParseUnattendedLog(t *testing.T) {
+	run := parseUnattendedLog(testUnattendedLog)
+	require.NotNil(t, run)
+
+	started, err := time.ParseInLocation(unattendedTimeLayout, "2026-10-15 06:25:12,345", time.Local)
+	require.NoError(t, err)
+	assert.Equal(t, started, run.Started)
+	assert.Equal(t, unattendedSuccess, run.Result)
+	assert.Empty(t, run.Errors)
+	assert.Equal(t, []string{"libssl3t64", "openssl"}, run.Packages)
+
+	firstRun, _, _ := strings.Cut(testUnattendedLog, "2026-10-15")
+	failed := parseUnattendedLog(firstRun)
+	require.NotNil(t, failed)
+	assert.Equal(t, unattendedFailed, failed.Result)
+	assert.Equal(t, []string{
+		"Installing the upgrades failed!",
+		"error message: 'E:Sub-process /usr/bin/dpkg returned an error code (1)'",
+	}, failed.Errors)
+
+	incomplete := parseUnattendedLog("2026-10-16 06:01:00,000 INFO Starting unattended upgrades script\n")
+	require.NotNil(t, incomplete)
+	assert.Equal(t, unattendedIncomplete, incomplete.Result)
+
+	assert.Nil(t, parseUnattendedLog(""))
+}
+
+// TestMatchOriginPattern ensures Origins-Pattern entries match releases like unattended-upgrades does
+func TestMatchOriginPattern(t *testing.T) {
+	security := releaseFields{Origin: "Debian", Label: "Debian-Security", Archive: "stable-security", Codename: "bookworm-security"}
+
+	assert.True(t, matchOriginPattern("origin=Debian,codename=bookworm-security,label=Debian-Security", security))
+	assert.True(t, matchOriginPattern("o=Debian,a=*-security", security))
+	assert.False(t, matchOriginPattern("o=Debian,a=stable-updates", security))
+	assert.False(t, matchOriginPattern("o=Debian,unknown=x", security))
+	assert.True(t, matchOriginPattern(`o=Vendor\, Inc.`, releaseFields{Origin: "Vendor, Inc."}))
+	assert.Equal(t, []string{"o=Vendor, Inc.", "a=stable"}, splitOriginPattern(`o=Vendor\, Inc.,a=stable`))
+}
+
+// TestUnattendedStatus ensures the configuration, the last run and the skipped security updates are reported
+func TestUnattendedStatus(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{
+			"usr/bin/unattended-upgrade":                          {Data: []byte("#!/usr/bin/python3\n")},
+			"etc/os-release":                                      {Data: []byte("NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_CODENAME=noble\n")},
+			"var/log/unattended-upgrades/unattended-upgrades.log": {Data: []byte(testUnattendedLog)},
+		},
+		output: testAptConfigDump,
+	}}
+
+	security := []UpdateInfo{
+		{
+			Name: "openssl", Target: "3.0.13-0ubuntu3.5", Repository: "Ubuntu noble-security",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-security", Codename: "noble"}},
+		},
+		{
+			Name: "php8.3-cli", Target: "8.3.12-1+ubuntu24.04.1+deb.sury.org+1", Repository: "ppa:ondrej/php",
+			releases: []releaseFields{{Origin: "LP-PPA-ondrej-php", Archive: "noble", Site: "ppa.launchpadcontent.net"}},
+		},
+		{
+			Name: "docker-ce", Target: "5:27.3.1-1~ubuntu.24.04~noble", Repository: "Docker noble-security",
+			releases: []releaseFields{{Origin: "Docker", Archive: "noble-security"}},
+		},
+		{
+			Name: "linux-image-generic", Target: "6.8.0-51.52", Repository: "Ubuntu noble-security",
+			releases: []releaseFields{{Origin: "Ubuntu", Archive: "noble-security", Codename: "noble"}},
+		},
+		{
+			// Classified from the Inst line only, the origin is unknown
+			Name: "vendor-agent", Target: "2.1-1", Repository: "Vendor stable-security",
+			releases: []releaseFields{{Label: "Vendor", Archive: "stable-security"}},
+		},
+	}
+
+	status, err := handler.unattendedStatus(context.Background(), security)
+	require.NoError(t, err)
+
+	assert.True(t, status.Installed)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, unattendedSuccess, status.LastRunResult)
+	assert.True(t, status.LastRunSuccess)
+	assert.Equal(t, []string{"libssl3t64", "openssl"}, status.InstalledPackages)
+	assert.Equal(t, []string{
+		"o=Ubuntu,a=noble",
+		"o=Ubuntu,a=noble-security",
+		"o=UbuntuESMApps,a=noble-apps-security",
+		"site=ppa.launchpadcontent.net,o=LP-PPA-ondrej-*",
+	}, status.Origins)
+	assert.Equal(t, []string{"linux-"}, status.PackageBlacklist)
+
+	assert.Equal(t, 2, status.SkippedSecurityUpdatesCount)
+	assert.Equal(t, []SkippedUpdate{
+		{Name: "docker-ce", Target: "5:27.3.1-1~ubuntu.24.04~noble", Repository: "Docker noble-security", Reason: skipOriginNotAllowed},
+		{Name: "linux-image-generic", Target: "6.8.0-51.52", Repository: "Ubuntu noble-security", Reason: skipBlacklisted},
+	}, status.SkippedSecurityUpdates)
+}
+
+// TestUnattendedStatusNotInstalled ensures unattended-upgrades is reported disabled when it is not installed
+func TestUnattendedStatusNotInstalled(t *testing.T) {
+	handler := &Handler{sysCalls: &mockSystemCalls{
+		mockFiles: mockFiles{},
+		output:    "APT::Periodic::Unattended-Upgrade \"0\";\n",
+	}}
+
+	status, err := handler.unattendedStatus(context.Background(), nil)
+	require.NoError(t, err)
+
+	assert.False(t, status.Installed)
+	assert.False(t, status.Enabled)
+	assert.Zero(t, status.LastRun)
+	assert.Empty(t, status.LastRunResult)
+	assert.Equal(t, []string{}, status.Origins)
+	assert.Equal(t, []SkippedUpdate{}, status.SkippedSecurityUpdates)
+}
+
+// TestGetUnattendedUpgradesFailed ensures a failure to read the configuration is reported by the item and in the snapshot
+func TestGetUnattendedUpgradesFailed(t *testing.T) {
+	handler := &Handler{sysCalls: &failingConfigSystemCalls{}}
+
+	_, err := handler.GetUnattendedUpgrades(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to execute apt-config dump")
+
+	res, err := handler.GetAllUpdates(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Contains(t, res.(*AllUpdatesResult).SectionErrors, "unattended")
+}
+
+// failingConfigSystemCalls fails apt-config and reports no updates
+type failingConfigSystemCalls struct {
+	mockFiles
+}
+
+func (f *failingConfigSystemCalls) execCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if name == "apt-config" {
+		return nil, errors.New("exit status 100")
+	}
+
+	return []byte{}, nil
+}
@@ -0,0 +1,212 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.zabbix.com/sdk/errs"
+)
+
+// debianVersion is a parsed Debian package version: [epoch:]upstream_version[-debian_revision]
+type debianVersion struct {
+	Epoch    int
+	Upstream string
+	Revision string
+}
+
+// parseDebianVersion splits a version string into epoch, upstream version and
+// revision following the rules of dpkg's parseversion
+func parseDebianVersion(s string) (debianVersion, error) {
+	var v debianVersion
+
+	s = strings.TrimSpace(s)
+	full := s
+
+	if s == "" {
+		return v, errs.New("version string is empty")
+	}
+
+	if strings.ContainsAny(s, " \t") {
+		return v, errs.New("version string has embedded spaces")
+	}
+
+	if epoch, rest, ok := strings.Cut(s, ":"); ok {
+		n, err := strconv.Atoi(epoch)
+		if err != nil || n < 0 {
+			return v, errs.Errorf("epoch in version %q is not a non-negative number", full)
+		}
+
+		v.Epoch = n
+		s = rest
+	}
+
+	// The revision is everything after the last hyphen
+	if i := strings.LastIndexByte(s, '-'); i >= 0 {
+		v.Revision = s[i+1:]
+		s = s[:i]
+
+		if v.Revision == "" {
+			return v, errs.Errorf("revision in version %q is empty", full)
+		}
+	}
+
+	if s == "" {
+		return v, errs.Errorf("upstream version in %q is empty", full)
+	}
+
+	v.Upstream = s
+
+	return v, nil
+}
+
+// String formats the version as dpkg prints it, omitting a zero epoch
+func (v debianVersion) String() string {
+	var b strings.Builder
+
+	if v.Epoch != 0 {
+		b.WriteString(strconv.Itoa(v.Epoch))
+		b.WriteByte(':')
+	}
+
+	b.WriteString(v.Upstream)
+
+	if v.Revision != "" {
+		b.WriteByte('-')
+		b.WriteString(v.Revision)
+	}
+
+	return b.String()
+}
+
+// compare returns a negative number, zero or a positive number when v is
+// older than, equal to or newer than other
+func (v debianVersion) compare(other debianVersion) int {
+	if v.Epoch != other.Epoch {
+		if v.Epoch < other.Epoch {
+			return -1
+		}
+
+		return 1
+	}
+
+	if r := compareVersionPart(v.Upstream, other.Upstream); r != 0 {
+		return r
+	}
+
+	return compareVersionPart(v.Revision, other.Revision)
+}
+
+// compareVersions compares two version strings like `dpkg --compare-versions`.
+// Versions that cannot be parsed sort before valid ones and are otherwise
+// compared as plain version parts.
+func compareVersions(a, b string) int {
+	va, errA := parseDebianVersion(a)
+	vb, errB := parseDebianVersion(b)
+
+	switch {
+	case errA != nil && errB != nil:
+		return compareVersionPart(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+
+	return va.compare(vb)
+}
+
+// compareVersionPart is dpkg's verrevcmp: the strings are compared as
+// alternating runs of non-digits, ordered by versionCharOrder, and digits,
+// compared numerically
+func compareVersionPart(a, b string) int {
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		firstDiff := 0
+
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := versionCharOrder(a, i), versionCharOrder(b, j)
+			if ac != bc {
+				return ac - bc
+			}
+
+			i++
+			j++
+		}
+
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[i]) - int(b[j])
+			}
+
+			i++
+			j++
+		}
+
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+
+	return 0
+}
+
+// versionCharOrder ranks the character at s[i]: a tilde sorts before
+// everything, even the end of the string, and letters sort before all other
+// characters
+func versionCharOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+
+	c := s[i]
+
+	switch {
+	case isDigit(c):
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
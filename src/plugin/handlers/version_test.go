@@ -0,0 +1,115 @@
+/*
+** Copyright (C) 2001-2026 Zabbix SIA
+**
+** Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated
+** documentation files (the "Software"), to deal in the Software without restriction, including without limitation the
+** rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to
+** permit persons to whom the Software is furnished to do so, subject to the following conditions:
+**
+** The above copyright notice and this permission notice shall be included in all copies or substantial portions
+** of the Software.
+**
+** THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE
+** WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+** COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT,
+** TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+** SOFTWARE.
+**/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompareVersions checks version ordering against results of `dpkg --compare-versions`
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "2.0", -1},
+		{"2.30", "2.1", 1},
+		{"2.10", "2.9", 1},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-10", "1.0-9", 1},
+		{"1.0-0", "1.0", 0},
+		{"1.0-00", "1.0-0", 0},
+		{"00.0-0", "0.0-0", 0},
+		{"0:1.0", "1.0", 0},
+		{"1:1.0", "2.0", 1},
+		{"2:0-0", "1:0-0", 1},
+		{"1:2.0", "1:10.0", -1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0~~a", "1.0~~", 1},
+		{"1.0~", "1.0", -1},
+		{"1.0", "1.0a", -1},
+		{"1.0a", "1.0+", -1},
+		{"1.0+dfsg", "1.0", 1},
+		{"1.0+dfsg-1", "1.0-1", 1},
+		{"1.0.1", "1.0+1", 1},
+		{"1.0-1ubuntu1", "1.0-1", 1},
+		{"1.0-1ubuntu1", "1.0-1build1", 1},
+		{"1.0-1~bpo12+1", "1.0-1", -1},
+		{"1.0-1+deb12u1", "1.0-1", 1},
+		{"1.0-1+deb12u10", "1.0-1+deb12u9", 1},
+		{"0-a", "0-b", -1},
+		{"1.2.3", "1.2.3.0", -1},
+		{"1.2.3-1", "1.2.3.1-1", -1},
+		{"3.0.13-0ubuntu3.5", "3.0.13-0ubuntu3.4", 1},
+		{"3.0.17-1~deb12u2", "3.0.14-1~deb12u2", 1},
+		{"2.36-9+deb12u13", "2.36-9+deb12u7", 1},
+		{"5:27.3.1-1~ubuntu.24.04~noble", "5:27.3.0-1~ubuntu.24.04~noble", 1},
+		{"1:9.6p1-3ubuntu13.5", "1:9.6p1-3ubuntu13", 1},
+		{"6.8.0-45.45", "6.8.0-100.100", -1},
+		{"7.94+git20230807.3be01efb1+dfsg-3build2", "7.94+git20230807.3be01efb1+dfsg-3build1", 1},
+		{"1.0-1.1", "1.0-1", 1},
+		{"1.0-1.1", "1.0-1+b1", 1},
+		{"2.4.58-1ubuntu8.4", "2.4.58-1ubuntu8.10", -1},
+		{"0.9~beta", "0.9~alpha", 1},
+		{"1.18.0+ds-1", "1.18.0~rc1+ds-1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.want, sign(compareVersions(tt.a, tt.b)))
+			assert.Equal(t, -tt.want, sign(compareVersions(tt.b, tt.a)), "comparison must be antisymmetric")
+		})
+	}
+}
+
+// TestParseDebianVersion ensures versions are split like dpkg's parseversion
+func TestParseDebianVersion(t *testing.T) {
+	v, err := parseDebianVersion("1:9.6p1-3ubuntu13.5")
+	require.NoError(t, err)
+	assert.Equal(t, debianVersion{Epoch: 1, Upstream: "9.6p1", Revision: "3ubuntu13.5"}, v)
+	assert.Equal(t, "1:9.6p1-3ubuntu13.5", v.String())
+
+	// Hyphens are allowed in the upstream version when there is a revision
+	v, err = parseDebianVersion("0:2.0-rc1-2")
+	require.NoError(t, err)
+	assert.Equal(t, debianVersion{Upstream: "2.0-rc1", Revision: "2"}, v)
+	assert.Equal(t, "2.0-rc1-2", v.String())
+
+	for _, invalid := range []string{"", "a:1.0", "-1:1.0", "1.0-", "1:", "1.0 1"} {
+		_, err := parseDebianVersion(invalid)
+		assert.Error(t, err, "version %q should be rejected", invalid)
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -21,13 +21,32 @@ package params
 import "golang.zabbix.com/sdk/metric"
 
 const (
+	// Type is the name of the parameter selecting the update category.
+	Type = "type"
+	// Phased is the name of the parameter controlling whether phased updates are included.
+	Phased = "phased"
+	// Mode is the name of the parameter selecting the simulated upgrade: upgrade or full-upgrade.
+	Mode = "mode"
 )
 
 //nolint:gochecknoglobals // global constants.
 var (
+	typeParam = metric.NewParam(Type,
+		"Type of updates to check: all, security, recommended, optional, or a category of the configured rules.").
+			WithDefault("all").
+			WithValidator(metric.PatternValidator{Pattern: `^[A-Za-z0-9_.-]+$`})
+
+	phasedParam = metric.NewParam(Phased, "Phased updates handling: include-phased or exclude-phased.").
+			WithDefault("exclude-phased").
+			WithValidator(metric.SetValidator{Set: []string{"include-phased", "exclude-phased"}})
+
+	modeParam = metric.NewParam(Mode, "Simulated upgrade: upgrade, or full-upgrade to include kept back packages.").
+			WithDefault("upgrade").
+			WithValidator(metric.SetValidator{Set: []string{"upgrade", "full-upgrade"}})
+
 	// Params groups all base parameters for APT updates plugin.
-	Params = []*metric.Param{
-		metric.NewConnParam("type", "Type of updates to check: all, security, recommended, or optional").
-			WithDefault("all"),
-	}
+	Params = []*metric.Param{typeParam, phasedParam, modeParam}
+
+	// DiscoveryParams groups the parameters of the update discovery metric.
+	DiscoveryParams = []*metric.Param{typeParam}
 )
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"zabbix-agent2-apt-updates/src/plugin/handlers"
+	"zabbix-agent2-apt-updates/src/plugin/params"
 	"golang.zabbix.com/sdk/errs"
 	"golang.zabbix.com/sdk/log"
 	"golang.zabbix.com/sdk/metric"
@@ -35,7 +36,25 @@ const (
 	// Name of the plugin.
 	Name = "APTUpdates"
 
-	allMetric = aptMetricKey("updates.get")
+	allMetric        = aptMetricKey("updates.get")
+	countMetric      = aptMetricKey("updates.count")
+	listMetric       = aptMetricKey("updates.list")
+	detailsMetric    = aptMetricKey("updates.details")
+	discoveryMetric  = aptMetricKey("updates.discovery")
+	reposMetric      = aptMetricKey("updates.repositories.discovery")
+	rebootMetric     = aptMetricKey("updates.reboot_required")
+	kernelMetric     = aptMetricKey("updates.kernel")
+	restartMetric    = aptMetricKey("updates.restart_required")
+	heldMetric       = aptMetricKey("updates.held")
+	unattendedMetric = aptMetricKey("updates.unattended")
+	historyMetric    = aptMetricKey("updates.history")
+	dpkgHealthMetric = aptMetricKey("updates.dpkg_health")
+	conffilesMetric  = aptMetricKey("updates.conffiles")
+	autoremoveMetric = aptMetricKey("updates.autoremovable")
+	obsoleteMetric   = aptMetricKey("updates.obsolete")
+	pinsMetric       = aptMetricKey("updates.pin_anomalies")
+	sourcesMetric    = aptMetricKey("updates.sources")
+	sourcesLLDMetric = aptMetricKey("updates.sources.discovery")
 )
 
 var (
@@ -55,6 +74,7 @@ type aptMetric struct {
 type APTUpdatesPlugin struct {
 	plugin.Base
 	config  *pluginConfig
+	handler *handlers.Handler
 	metrics map[aptMetricKey]*aptMetric
 }
 
@@ -72,7 +92,7 @@ func New() (*APTUpdatesPlugin, error) {
 	// Initialize config with defaults
 	p.config = &pluginConfig{
 		Sessions: make(map[string]session),
-		Default: session{},
+		Default:  session{},
 	}
 
 	err = p.registerMetrics()
@@ -101,14 +121,30 @@ func (p *APTUpdatesPlugin) Run() error {
 	return nil
 }
 
-// Start starts the APTUpdates plugin. Is required for plugin to match runner interface.
+// Start starts the APTUpdates plugin. Launches the background collector so
+// metrics are served from a snapshot instead of running apt inline.
 func (p *APTUpdatesPlugin) Start() {
-	p.Infof("Start called")
+	if p.config.RefreshInterval <= 0 {
+		// Configuration was not applied, keep checking updates inline
+		p.Errf("Start called without a refresh interval, checking updates on every request")
+
+		return
+	}
+
+	p.Infof("Start called, refreshing updates every %d seconds", p.config.RefreshInterval)
+
+	p.handler.StartRefresh(
+		time.Duration(p.config.RefreshInterval)*time.Second,
+		time.Duration(p.config.RefreshTimeout)*time.Second,
+		p.Logger,
+	)
 }
 
-// Stop stops the APTUpdates plugin. Is required for plugin to match runner interface.
+// Stop stops the APTUpdates plugin and its background collector.
 func (p *APTUpdatesPlugin) Stop() {
 	p.Infof("Stop called")
+
+	p.handler.StopRefresh()
 }
 
 // Export collects all the metrics.
@@ -147,7 +183,8 @@ func (p *APTUpdatesPlugin) Export(key string, rawParams []string, _ plugin.Conte
 }
 
 func (p *APTUpdatesPlugin) registerMetrics() error {
-	handler := handlers.New()
+	p.handler = handlers.New()
+	handler := p.handler
 
 	p.metrics = map[aptMetricKey]*aptMetric{
 		allMetric: {
@@ -158,6 +195,150 @@ func (p *APTUpdatesPlugin) registerMetrics() error {
 			),
 			handler: handlers.WithJSONResponse(handler.GetAllUpdates),
 		},
+		countMetric: {
+			metric: metric.New(
+				"Returns the number of available APT updates of the given type.",
+				params.Params,
+				false,
+			),
+			handler: handler.CheckUpdateCount,
+		},
+		listMetric: {
+			metric: metric.New(
+				"Returns a JSON array with the names of available APT updates of the given type.",
+				params.Params,
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetUpdateList),
+		},
+		detailsMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the count, versions and check timing of available APT updates of the given type.",
+				params.Params,
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetUpdateDetails),
+		},
+		discoveryMetric: {
+			metric: metric.New(
+				"Returns low-level discovery data for pending package updates of the given type.",
+				params.DiscoveryParams,
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.DiscoverUpdates),
+		},
+		reposMetric: {
+			metric: metric.New(
+				"Returns low-level discovery data for the repositories pending package updates come from.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.DiscoverRepositories),
+		},
+		rebootMetric: {
+			metric: metric.New(
+				"Returns a JSON object telling whether a reboot is required, since when and which packages requested it.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetRebootRequired),
+		},
+		kernelMetric: {
+			metric: metric.New(
+				"Returns a JSON object comparing the running kernel with the installed kernels and pending kernel updates.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetKernelStatus),
+		},
+		restartMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the processes and services still using deleted or replaced libraries and executables.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetRestartRequired),
+		},
+		heldMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the packages on hold and the updates and security fixes the holds keep back.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetHeldPackages),
+		},
+		unattendedMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the unattended-upgrades configuration, its last run and the security updates it skips.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetUnattendedUpgrades),
+		},
+		historyMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the last package upgrade, install and removal, the recent apt transactions and the mean time to install updates.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetHistory),
+		},
+		dpkgHealthMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the packages dpkg left in intermediate states, the pending dpkg journal and the apt-get check errors.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetDpkgHealth),
+		},
+		conffilesMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the .dpkg-dist, .dpkg-new, .dpkg-old, .ucf-dist and .ucf-new files in /etc and their packages.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetConffileLeftovers),
+		},
+		autoremoveMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the automatically installed packages that are no longer needed, as apt autoremove would remove them.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetAutoremovable),
+		},
+		obsoleteMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the installed packages no configured repository provides.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetObsoletePackages),
+		},
+		pinsMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the packages newer than their repository versions and the security updates kept back by pin priorities.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetPinAnomalies),
+		},
+		sourcesMetric: {
+			metric: metric.New(
+				"Returns a JSON object with the repositories configured in the APT sources.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.GetSources),
+		},
+		sourcesLLDMetric: {
+			metric: metric.New(
+				"Returns low-level discovery data for the repositories configured in the APT sources.",
+				[]*metric.Param{},
+				false,
+			),
+			handler: handlers.WithJSONResponse(handler.DiscoverSources),
+		},
 	}
 
 	metricSet := metric.MetricSet{}